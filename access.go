@@ -0,0 +1,273 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessRecord is an access log record of a single call.
+type AccessRecord struct {
+	// Time is the start time of the call.
+	Time time.Time
+
+	// Method is the name of the method called.
+	Method string
+
+	// Status is the response status code.
+	Status int
+
+	// Error is the error type, or an empty string when successful.
+	Error string
+
+	// Duration is the duration of the call.
+	Duration time.Duration
+
+	// RequestBytes is the size of the request body read, as sent.
+	RequestBytes int64
+
+	// ResponseBytes is the size of the response body written, as sent,
+	// which is zero for calls of batch requests and WebSocket connections.
+	ResponseBytes int64
+
+	// RequestID is the request ID, if any.
+	RequestID string
+
+	// Caller is the identity of the caller, if any.
+	Caller string
+
+	// Slow is true when the call exceeded the slow threshold.
+	Slow bool
+}
+
+// accessRecordJSON is the JSON representation of an access record.
+type accessRecordJSON struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	Status        int       `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	DurationMS    float64   `json:"duration_ms"`
+	RequestBytes  int64     `json:"request_bytes"`
+	ResponseBytes int64     `json:"response_bytes"`
+	RequestID     string    `json:"request_id,omitempty"`
+	Caller        string    `json:"caller,omitempty"`
+	Slow          bool      `json:"slow,omitempty"`
+}
+
+// MarshalJSON implementation.
+func (a AccessRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(accessRecordJSON{
+		Time:          a.Time,
+		Method:        a.Method,
+		Status:        a.Status,
+		Error:         a.Error,
+		DurationMS:    float64(a.Duration) / float64(time.Millisecond),
+		RequestBytes:  a.RequestBytes,
+		ResponseBytes: a.ResponseBytes,
+		RequestID:     a.RequestID,
+		Caller:        a.Caller,
+		Slow:          a.Slow,
+	})
+}
+
+// AccessSink is the interface used for writing access records.
+type AccessSink interface {
+	WriteAccess(AccessRecord)
+}
+
+// jsonAccessSink writes JSON lines.
+type jsonAccessSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONAccessSink returns a sink writing access records to w as JSON lines.
+func NewJSONAccessSink(w io.Writer) AccessSink {
+	return &jsonAccessSink{w: w}
+}
+
+// WriteAccess implementation.
+func (s *jsonAccessSink) WriteAccess(a AccessRecord) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(append(b, '\n'))
+}
+
+// slogAccessSink writes slog records.
+type slogAccessSink struct {
+	logger *slog.Logger
+}
+
+// NewSlogAccessSink returns a sink writing access records to logger, at the
+// error level for server errors, the warn level for slow calls, and the info
+// level otherwise.
+func NewSlogAccessSink(logger *slog.Logger) AccessSink {
+	return slogAccessSink{logger: logger}
+}
+
+// WriteAccess implementation.
+func (s slogAccessSink) WriteAccess(a AccessRecord) {
+	level := slog.LevelInfo
+	switch {
+	case a.Status >= 500:
+		level = slog.LevelError
+	case a.Slow:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", a.Method),
+		slog.Int("status", a.Status),
+		slog.Duration("duration", a.Duration),
+		slog.Int64("request_bytes", a.RequestBytes),
+		slog.Int64("response_bytes", a.ResponseBytes),
+	}
+
+	if a.Error != "" {
+		attrs = append(attrs, slog.String("error", a.Error))
+	}
+
+	if a.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", a.RequestID))
+	}
+
+	if a.Caller != "" {
+		attrs = append(attrs, slog.String("caller", a.Caller))
+	}
+
+	if a.Slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+
+	s.logger.LogAttrs(context.Background(), level, "rpc call", attrs...)
+}
+
+// AccessLog is an access log writing one record per call.
+type AccessLog struct {
+	// Sink is the sink records are written to.
+	Sink AccessSink
+
+	// SampleRate is the fraction of successful calls logged, between 0 and 1,
+	// where zero logs all calls. Failed and slow calls are always logged.
+	SampleRate float64
+
+	// SlowThreshold is the duration above which calls are marked slow,
+	// zero disables it.
+	SlowThreshold time.Duration
+
+	// Caller returns the identity of the caller of r, if any.
+	Caller func(r *http.Request) string
+}
+
+// DefaultAccessLog is the access log used by LogAccess, calls are not logged when nil.
+var DefaultAccessLog *AccessLog
+
+// sampled returns true if a should be logged.
+func (l *AccessLog) sampled(a AccessRecord) bool {
+	if a.Error != "" || a.Slow {
+		return true
+	}
+
+	if l.SampleRate <= 0 || l.SampleRate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < l.SampleRate
+}
+
+// LogAccess starts an access record of the call of request r using
+// DefaultAccessLog, returning a function which must be deferred to write it.
+// The status, error type and response size are recorded when w was returned
+// by NewResponseWriter. Panics are recorded as internal errors, and then
+// propagated. Calls of batch requests and WebSocket connections are recorded
+// individually, without a response size.
+//
+//	defer rpc.LogAccess(w, r)()
+func LogAccess(w http.ResponseWriter, r *http.Request) func() {
+	done := startAccess(r)
+	if done == nil {
+		return func() {}
+	}
+
+	return func() {
+		v := recover()
+
+		var a AccessRecord
+		if rw, ok := w.(*responseWriter); ok {
+			a.Status = rw.status
+			a.Error = rw.errorType
+			a.ResponseBytes = rw.written
+		}
+
+		if v != nil {
+			a.Status = http.StatusInternalServerError
+			a.Error = "internal"
+		}
+
+		done(a)
+
+		if v != nil {
+			panic(v)
+		}
+	}
+}
+
+// startAccess starts an access record of the call of request r using
+// DefaultAccessLog, returning a function writing it with the status, error
+// type and response size of a, or nil when calls are not logged.
+func startAccess(r *http.Request) func(a AccessRecord) {
+	l := DefaultAccessLog
+	if l == nil || l.Sink == nil {
+		return nil
+	}
+
+	start := time.Now()
+
+	body := &countingReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+
+	return func(a AccessRecord) {
+		a.Time = start
+		a.Method = strings.TrimPrefix(r.URL.Path, "/")
+		a.Duration = time.Since(start)
+		a.RequestBytes = body.n
+		a.RequestID, _ = RequestIDFromContext(r.Context())
+
+		if l.Caller != nil {
+			a.Caller = l.Caller(r)
+		}
+
+		if l.SlowThreshold > 0 && a.Duration > l.SlowThreshold {
+			a.Slow = true
+		}
+
+		if l.sampled(a) {
+			l.Sink.WriteAccess(a)
+		}
+	}
+}
+
+// countingReader counts the bytes read.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read implementation.
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,134 @@
+package rpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// call performs a logged call of handler h.
+func call(h func(w http.ResponseWriter, r *http.Request)) {
+	r := httptest.NewRequest("POST", "/add_item", strings.NewReader(`{ "item": "cook" }`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(rpc.RequestIDHeader, "abc")
+	r = rpc.WithRequestID(r)
+	w := rpc.NewResponseWriter(httptest.NewRecorder(), r, rpc.CompressionThreshold(0))
+	defer rpc.LogAccess(w, r)()
+	h(w, r)
+}
+
+// Test access logging.
+func TestLogAccess(t *testing.T) {
+	var buf bytes.Buffer
+	rpc.DefaultAccessLog = &rpc.AccessLog{
+		Sink: rpc.NewJSONAccessSink(&buf),
+		Caller: func(r *http.Request) string {
+			return "tobi"
+		},
+	}
+	defer func() { rpc.DefaultAccessLog = nil }()
+
+	call(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Item string }
+		assert.NoError(t, rpc.ReadRequest(r, &in))
+		rpc.WriteResponse(w, nil)
+	})
+
+	call(func(w http.ResponseWriter, r *http.Request) {
+		rpc.WriteError(w, rpc.Invalid("Item is required"))
+	})
+
+	t.Run("with a panic", func(t *testing.T) {
+		assert.Panics(t, func() {
+			call(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})
+		})
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+
+	var records []map[string]interface{}
+	for _, line := range lines {
+		var v map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &v))
+		assert.Contains(t, v, "time")
+		assert.Contains(t, v, "duration_ms")
+		delete(v, "time")
+		delete(v, "duration_ms")
+		records = append(records, v)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"method":         "add_item",
+		"status":         204.0,
+		"request_bytes":  18.0,
+		"response_bytes": 0.0,
+		"request_id":     "abc",
+		"caller":         "tobi",
+	}, records[0])
+
+	assert.Equal(t, "invalid", records[1]["error"])
+	assert.Equal(t, 400.0, records[1]["status"])
+	assert.NotEqual(t, 0.0, records[1]["response_bytes"])
+
+	assert.Equal(t, "internal", records[2]["error"])
+	assert.Equal(t, 500.0, records[2]["status"])
+}
+
+// Test access log sampling.
+func TestLogAccess_sampling(t *testing.T) {
+	var buf bytes.Buffer
+	rpc.DefaultAccessLog = &rpc.AccessLog{
+		Sink:          rpc.NewJSONAccessSink(&buf),
+		SampleRate:    0.000001,
+		SlowThreshold: 5 * time.Millisecond,
+	}
+	defer func() { rpc.DefaultAccessLog = nil }()
+
+	for i := 0; i < 10; i++ {
+		call(func(w http.ResponseWriter, r *http.Request) {
+			rpc.WriteResponse(w, nil)
+		})
+	}
+
+	call(func(w http.ResponseWriter, r *http.Request) {
+		rpc.WriteError(w, rpc.Invalid("Item is required"))
+	})
+
+	call(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		rpc.WriteResponse(w, nil)
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"error":"invalid"`)
+	assert.Contains(t, lines[1], `"slow":true`)
+}
+
+// Test access logging with slog.
+func TestNewSlogAccessSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := rpc.NewSlogAccessSink(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	sink.WriteAccess(rpc.AccessRecord{
+		Method:    "add_item",
+		Status:    500,
+		Error:     "internal",
+		Duration:  time.Second,
+		RequestID: "abc",
+	})
+
+	assert.Contains(t, buf.String(), `level=ERROR msg="rpc call" method=add_item status=500 duration=1s request_bytes=0 response_bytes=0 error=internal request_id=abc`)
+}
@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultMaxBatchSize is the default maximum number of calls in a batch.
+const DefaultMaxBatchSize = 50
+
+// CallFunc invokes the method at path with request r, where w is used by
+// streaming methods, and is nil for calls of batch requests and WebSocket
+// connections.
+type CallFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (interface{}, error)
+
+// BatchOption is a ServeBatch option.
+type BatchOption func(*batchConfig)
+
+// batchConfig is the ServeBatch configuration.
+type batchConfig struct {
+	concurrency int
+	maxSize     int
+	read        []ReadOption
+}
+
+// BatchConcurrency sets the maximum number of calls of a batch run
+// concurrently, defaulting to running them sequentially.
+func BatchConcurrency(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.concurrency = n
+	}
+}
+
+// MaxBatchSize sets the maximum number of calls in a batch, defaulting to DefaultMaxBatchSize.
+func MaxBatchSize(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.maxSize = n
+	}
+}
+
+// BatchReadOptions sets the options used to read batch requests, such as Strict,
+// where the body size is limited to DefaultMaxBodyBytes unless MaxBodyBytes is set.
+func BatchReadOptions(options ...ReadOption) BatchOption {
+	return func(c *batchConfig) {
+		c.read = append(c.read, options...)
+	}
+}
+
+// batchCall is a call of a batch request.
+type batchCall struct {
+	Method string             `json:"method"`
+	Input  stdjson.RawMessage `json:"input"`
+}
+
+// CallResult is the result of a call of a batch request or WebSocket connection,
+// with a status code and either the result or an error in the WriteError shape.
+type CallResult struct {
+	Status int            `json:"status"`
+	Result interface{}    `json:"result,omitempty"`
+	Error  *ErrorResponse `json:"error,omitempty"`
+}
+
+// ServeBatch serves a batch request r, a JSON array of calls with a method
+// name and input, invoking each with call and responding with an array of
+// results with a status code and either the result or an error in the
+// WriteError shape. The calls are passed a copy of r with the input as its
+// body, and panics are recovered per call.
+func ServeBatch(w http.ResponseWriter, r *http.Request, call CallFunc, options ...BatchOption) {
+	c := batchConfig{
+		concurrency: 1,
+		maxSize:     DefaultMaxBatchSize,
+		read:        []ReadOption{MaxBodyBytes(DefaultMaxBodyBytes)},
+	}
+
+	for _, o := range options {
+		o(&c)
+	}
+
+	var calls []batchCall
+	err := ReadRequest(r, &calls, c.read...)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if len(calls) > c.maxSize {
+		WriteError(w, Invalid(fmt.Sprintf("Batch must not exceed %d calls", c.maxSize)))
+		return
+	}
+
+	concurrency := c.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]CallResult, len(calls))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, bc := range calls {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, bc batchCall) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = InvokeCall(r, bc.Method, bc.Input, call)
+		}(i, bc)
+	}
+	wg.Wait()
+
+	WriteResponse(w, results)
+}
+
+// InvokeCall invokes method with input using call, passing a copy of request r
+// with the input as its body and a nil response writer. Panics are recovered
+// as internal errors, errors are reported to OnError, and the access record
+// of the call is written when DefaultAccessLog is set.
+func InvokeCall(r *http.Request, method string, input []byte, call CallFunc) CallResult {
+	return invokeCall(callRequest(r, method, input), call)
+}
+
+// ErrorResult returns the result of a call of request r failing with err.
+func ErrorResult(r *http.Request, err error) CallResult {
+	status, body := errorResponse(r, err)
+	return CallResult{Status: status, Error: &body}
+}
+
+// callRequest returns a copy of r for a call of method with input as its body.
+func callRequest(r *http.Request, method string, input []byte) *http.Request {
+	if len(input) == 0 || string(input) == "null" {
+		input = []byte("{}")
+	}
+
+	sub := r.Clone(r.Context())
+	sub.URL.Path = "/" + method
+	sub.Body = io.NopCloser(bytes.NewReader(input))
+	sub.ContentLength = int64(len(input))
+	sub.Header.Set("Content-Type", "application/json")
+	sub.Header.Del("Content-Encoding")
+	return sub
+}
+
+// invokeCall invokes call with request r, returning its result, and
+// writes its access record when DefaultAccessLog is set.
+func invokeCall(r *http.Request, call CallFunc) (result CallResult) {
+	if done := startAccess(r); done != nil {
+		defer func() {
+			a := AccessRecord{Status: result.Status}
+			if result.Error != nil {
+				a.Error = result.Error.Type
+			}
+			done(a)
+		}()
+	}
+
+	res, err := invokeRecover(r, call)
+	if err != nil {
+		if OnError != nil {
+			OnError(r, err)
+		}
+		return ErrorResult(r, err)
+	}
+
+	if res == nil {
+		return CallResult{Status: http.StatusNoContent}
+	}
+
+	return CallResult{Status: http.StatusOK, Result: res}
+}
+
+// invokeRecover invokes call with request r, recovering panics as internal errors.
+func invokeRecover(r *http.Request, call CallFunc) (res interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = panicError(r, v)
+		}
+	}()
+
+	return call(NewRequestContext(r.Context(), r), nil, r, r.URL.Path)
+}
@@ -0,0 +1,174 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// batchCall implementation.
+func batchCall(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (interface{}, error) {
+	switch path {
+	case "/add_item":
+		var in struct {
+			Item string `json:"item"`
+		}
+		err := rpc.ReadRequest(r, &in)
+		if err != nil {
+			return nil, err
+		}
+		if in.Item == "" {
+			return nil, rpc.ValidationErrors{{Field: "item", Message: "is required"}}
+		}
+		return nil, nil
+	case "/get_items":
+		return map[string]interface{}{"items": []string{"cook"}}, nil
+	case "/panic":
+		panic("boom")
+	default:
+		return nil, rpc.ErrInvalidMethod
+	}
+}
+
+// serveBatch serves a batch request with body.
+func serveBatch(body string, options ...rpc.BatchOption) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/_batch", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	rpc.ServeBatch(rpc.NewResponseWriter(w, r, rpc.CompressionThreshold(0)), r, batchCall, options...)
+	return w
+}
+
+// Test batch requests.
+func TestServeBatch(t *testing.T) {
+	defer func(fn func(*http.Request, rpc.PanicError)) {
+		rpc.OnPanic = fn
+	}(rpc.OnPanic)
+	rpc.OnPanic = nil
+
+	t.Run("with calls", func(t *testing.T) {
+		w := serveBatch(`[
+			{ "method": "add_item", "input": { "item": "cook" } },
+			{ "method": "add_item", "input": {} },
+			{ "method": "get_items" },
+			{ "method": "remove_item" },
+			{ "method": "panic" }
+		]`)
+		assert.Equal(t, 200, w.Code)
+
+		var results []map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+		assert.Equal(t, []map[string]interface{}{
+			{"status": 204.0},
+			{"status": 400.0, "error": map[string]interface{}{
+				"type":    "invalid",
+				"message": "item is required",
+				"fields":  []interface{}{map[string]interface{}{"field": "item", "message": "is required"}},
+			}},
+			{"status": 200.0, "result": map[string]interface{}{"items": []interface{}{"cook"}}},
+			{"status": 400.0, "error": map[string]interface{}{"type": "bad_request", "message": "Invalid method"}},
+			{"status": 500.0, "error": map[string]interface{}{"type": "internal", "message": "Internal server error"}},
+		}, results)
+	})
+
+	t.Run("with too many calls", func(t *testing.T) {
+		w := serveBatch(`[{ "method": "get_items" }, { "method": "get_items" }]`, rpc.MaxBatchSize(1))
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), "Batch must not exceed 1 calls")
+	})
+
+	t.Run("with a malformed body", func(t *testing.T) {
+		w := serveBatch(`{}`)
+		assert.Equal(t, 400, w.Code)
+	})
+
+	t.Run("with an unknown field in strict mode", func(t *testing.T) {
+		w := serveBatch(`[{ "method": "get_items", "params": {} }]`, rpc.BatchReadOptions(rpc.Strict()))
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), `Unknown field \"params\"`)
+	})
+
+	t.Run("with a body exceeding the limit", func(t *testing.T) {
+		w := serveBatch(`[{ "method": "get_items" }]`, rpc.BatchReadOptions(rpc.MaxBodyBytes(10)))
+		assert.Equal(t, 413, w.Code)
+		assert.Contains(t, w.Body.String(), "Request body must not exceed 10 bytes")
+	})
+}
+
+// Test access records of batch calls.
+func TestServeBatch_access(t *testing.T) {
+	defer func(fn func(*http.Request, rpc.PanicError)) {
+		rpc.OnPanic = fn
+	}(rpc.OnPanic)
+	rpc.OnPanic = nil
+
+	var buf bytes.Buffer
+	rpc.DefaultAccessLog = &rpc.AccessLog{Sink: rpc.NewJSONAccessSink(&buf)}
+	defer func() { rpc.DefaultAccessLog = nil }()
+
+	serveBatch(`[
+		{ "method": "add_item", "input": { "item": "cook" } },
+		{ "method": "add_item", "input": {} },
+		{ "method": "panic" }
+	]`)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var v map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &v))
+		records = append(records, v)
+	}
+
+	assert.Len(t, records, 3)
+	sort.Slice(records, func(i, j int) bool {
+		return records[i]["status"].(float64) < records[j]["status"].(float64)
+	})
+
+	assert.Equal(t, "add_item", records[0]["method"])
+	assert.Equal(t, 204.0, records[0]["status"])
+	assert.Equal(t, 18.0, records[0]["request_bytes"])
+
+	assert.Equal(t, "add_item", records[1]["method"])
+	assert.Equal(t, "invalid", records[1]["error"])
+
+	assert.Equal(t, "panic", records[2]["method"])
+	assert.Equal(t, "internal", records[2]["error"])
+}
+
+// Test batch concurrency.
+func TestServeBatch_concurrency(t *testing.T) {
+	var running, max int32
+	call := func(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil, nil
+	}
+
+	body := strings.Repeat(`{ "method": "a" },`, 8)
+	body = "[" + strings.TrimSuffix(body, ",") + "]"
+
+	r := httptest.NewRequest("POST", "/_batch", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	rpc.ServeBatch(w, r, call, rpc.BatchConcurrency(3))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, int32(3), max)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/apex/rpc/importers/golang"
+)
+
+func main() {
+	pkg := flag.String("package", ".", "Package pattern to inspect")
+	server := flag.String("server", "Server", "Name of the server type")
+	name := flag.String("name", "", "Name of the API, defaulting to the package name")
+	version := flag.String("version", "1.0.0", "Version of the API")
+	flag.Parse()
+
+	s, err := golang.Import(golang.Config{
+		Pattern: *pkg,
+		Server:  *server,
+		Name:    *name,
+		Version: *version,
+	})
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(s)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+}
@@ -15,6 +15,7 @@ import (
 func main() {
 	path := flag.String("schema", "schema.json", "Path to the schema file")
 	pkg := flag.String("package", "client", "Name of the package")
+	webSocket := flag.Bool("websocket", false, "Enable the WebSocket connection, which requires github.com/gorilla/websocket")
 	flag.Parse()
 
 	s, err := schema.Load(*path)
@@ -22,14 +23,14 @@ func main() {
 		log.Fatalf("error: %s", err)
 	}
 
-	err = generate(os.Stdout, s, *pkg)
+	err = generate(os.Stdout, s, *pkg, *webSocket)
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 }
 
 // generate implementation.
-func generate(w io.Writer, s *schema.Schema, pkg string) error {
+func generate(w io.Writer, s *schema.Schema, pkg string, webSocket bool) error {
 	out := fmt.Fprintf
 
 	// force tags to be json only
@@ -40,11 +41,27 @@ func generate(w io.Writer, s *schema.Schema, pkg string) error {
 
 	out(w, "import (\n")
 	out(w, "  \"bytes\"\n")
+	out(w, "  \"compress/gzip\"\n")
+	if webSocket {
+		out(w, "  \"context\"\n")
+	}
+	out(w, "  \"crypto/rand\"\n")
+	out(w, "  \"encoding/hex\"\n")
 	out(w, "  \"encoding/json\"\n")
+	out(w, "  \"errors\"\n")
 	out(w, "  \"fmt\"\n")
 	out(w, "  \"io\"\n")
 	out(w, "  \"net/http\"\n")
+	out(w, "  \"strconv\"\n")
+	if webSocket {
+		out(w, "  \"strings\"\n")
+		out(w, "  \"sync\"\n")
+	}
 	out(w, "  \"time\"\n")
+	if webSocket {
+		out(w, "\n")
+		out(w, "  \"github.com/gorilla/websocket\"\n")
+	}
 	out(w, ")\n\n")
 
 	err := gotypes.Generate(w, s, false)
@@ -57,5 +74,12 @@ func generate(w io.Writer, s *schema.Schema, pkg string) error {
 		return fmt.Errorf("generating client: %w", err)
 	}
 
+	if webSocket {
+		err = goclient.GenerateWebSocket(w, s)
+		if err != nil {
+			return fmt.Errorf("generating websocket client: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -17,6 +17,14 @@ func main() {
 	pkg := flag.String("package", "server", "Name of the package")
 	types := flag.String("types", "", "Types package to import")
 	logging := flag.Bool("logging", true, "Enable logging generation")
+	logger := flag.String("logger", "apex", "Logger used by logging generation, apex or slog")
+	strict := flag.Bool("strict", false, "Reject unknown fields, duplicate keys and trailing data in requests")
+	batch := flag.Bool("batch", false, "Enable the /_batch endpoint for multiple calls in one request")
+	batchConcurrency := flag.Int("batch-concurrency", 0, "Maximum number of calls of a batch run concurrently, zero runs them sequentially")
+	webSocket := flag.Bool("websocket", false, "Enable the /_websocket endpoint for calls and notifications over a WebSocket connection")
+	introspection := flag.Bool("introspection", false, "Enable the /_schema endpoint serving the schema, with private methods and types only for authorized requests")
+	metrics := flag.Bool("metrics", false, "Enable the /_metrics endpoint serving per-method metrics in the Prometheus text format")
+	compression := flag.Int("compression-threshold", 0, "Minimum size in bytes of compressed responses, zero uses the rpc package default and a negative value disables compression")
 	flag.Parse()
 
 	s, err := schema.Load(*path)
@@ -24,14 +32,24 @@ func main() {
 		log.Fatalf("error: %s", err)
 	}
 
-	err = generate(os.Stdout, s, *pkg, *types, *logging)
+	err = generate(os.Stdout, s, *pkg, *types, goserver.Options{
+		Tracing:              *logging,
+		Logger:               *logger,
+		Strict:               *strict,
+		Batch:                *batch,
+		BatchConcurrency:     *batchConcurrency,
+		WebSocket:            *webSocket,
+		Introspection:        *introspection,
+		Metrics:              *metrics,
+		CompressionThreshold: *compression,
+	})
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 }
 
 // generate implementation.
-func generate(w io.Writer, s *schema.Schema, pkg, types string, logging bool) error {
+func generate(w io.Writer, s *schema.Schema, pkg, types string, options goserver.Options) error {
 	out := fmt.Fprintf
 
 	// TODO: move these to generator
@@ -43,7 +61,16 @@ func generate(w io.Writer, s *schema.Schema, pkg, types string, logging bool) er
 	out(w, "  \"net/http\"\n")
 	out(w, "\n")
 	out(w, "  \"github.com/apex/rpc\"\n")
-	out(w, "  \"github.com/apex/log\"\n")
+	if options.WebSocket {
+		out(w, "  \"github.com/apex/rpc/transport/websocket\"\n")
+	}
+	if options.Tracing {
+		logger, err := options.LoggerImport()
+		if err != nil {
+			return err
+		}
+		out(w, "  \"%s\"\n", logger)
+	}
 	if len(types) > 0 {
 		out(w, "\n")
 		out(w, "  \"%s\"\n", types)
@@ -51,9 +78,9 @@ func generate(w io.Writer, s *schema.Schema, pkg, types string, logging bool) er
 	out(w, ")\n\n")
 
 	if len(types) > 0 {
-		types = path.Base(types)
+		options.Types = path.Base(types)
 	}
-	err := goserver.Generate(w, s, logging, types)
+	err := goserver.Generate(w, s, options)
 	if err != nil {
 		return fmt.Errorf("generating client: %w", err)
 	}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/apex/rpc/importers/openapi"
+)
+
+func main() {
+	path := flag.String("openapi", "openapi.yml", "Path to the OpenAPI 3 JSON or YAML document")
+	strict := flag.Bool("strict", false, "Fail when constructs cannot be represented")
+	flag.Parse()
+
+	b, err := os.ReadFile(*path)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	s, warnings, err := openapi.Import(b)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+
+	if *strict && len(warnings) > 0 {
+		log.Fatalf("error: %d unsupported constructs", len(warnings))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(s)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/apex/rpc/generators/jsonschema"
+	"github.com/apex/rpc/schema"
+)
+
+func main() {
+	path := flag.String("schema", "schema.json", "Path to the schema file")
+	out := flag.String("output", "jsonschema", "Output directory")
+	flag.Parse()
+
+	s, err := schema.Load(*path)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	println()
+	defer println()
+
+	err = jsonschema.Generate(s, *out)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	fmt.Printf("  ==> Complete\n")
+}
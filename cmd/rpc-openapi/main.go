@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/apex/rpc/generators/openapi"
+	"github.com/apex/rpc/schema"
+)
+
+func main() {
+	path := flag.String("schema", "schema.json", "Path to the schema file")
+	private := flag.Bool("private", false, "Include private methods and types")
+	flag.Parse()
+
+	s, err := schema.Load(*path)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	err = openapi.Generate(os.Stdout, s, *private)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+}
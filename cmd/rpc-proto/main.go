@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/apex/rpc/generators/proto"
+	"github.com/apex/rpc/schema"
+)
+
+func main() {
+	path := flag.String("schema", "schema.json", "Path to the schema file")
+	pkg := flag.String("package", "api", "Name of the package")
+	goPackage := flag.String("go-package", "", "Optional go_package option")
+	lockPath := flag.String("lock", "schema.proto.lock", "Path to the field number lock file")
+	flag.Parse()
+
+	s, err := schema.Load(*path)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	lock, err := proto.LoadLock(*lockPath)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	err = proto.Generate(os.Stdout, s, *pkg, *goPackage, lock)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	err = lock.Save(*lockPath)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+}
@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec is the interface used for encoding and decoding bodies of a media type.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+// codecs registered.
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{
+	m: map[string]Codec{
+		"application/json": JSONCodec{},
+	},
+}
+
+// RegisterCodec registers codec c for its content type, replacing any existing codec.
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[c.ContentType()] = c
+}
+
+// LookupCodec returns the codec registered for the media type of Content-Type header value s.
+func LookupCodec(s string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(s)
+	if err != nil {
+		return nil, false
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.m[mediaType]
+	return c, ok
+}
+
+// contentTypes returns the sorted content types of the registered codecs.
+func contentTypes() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	var list []string
+	for t := range codecs.m {
+		list = append(list, t)
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+// oneOf returns list formatted as alternatives, such as "a, b or c".
+func oneOf(list []string) string {
+	if len(list) < 2 {
+		return strings.Join(list, "")
+	}
+
+	return strings.Join(list[:len(list)-1], ", ") + " or " + list[len(list)-1]
+}
+
+// JSONCodec is the default application/json codec.
+type JSONCodec struct{}
+
+// ContentType implementation.
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// Marshal implementation.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// Unmarshal implementation.
+func (JSONCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+// negotiate returns the registered codec best matching the Accept header
+// value s, defaulting to JSON when nothing acceptable is registered.
+func negotiate(s string) Codec {
+	type accept struct {
+		mediaType string
+		q         float64
+	}
+
+	var list []accept
+	for _, part := range strings.Split(s, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+
+		if q > 0 {
+			list = append(list, accept{mediaType, q})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	for _, a := range list {
+		if a.mediaType == "*/*" || a.mediaType == "application/*" {
+			break
+		}
+
+		if c, ok := codecs.m[a.mediaType]; ok {
+			return c
+		}
+	}
+
+	return JSONCodec{}
+}
+
+// responseWriter is a response writer carrying the request used for negotiation.
+type responseWriter struct {
+	http.ResponseWriter
+	request              *http.Request
+	compressionThreshold int
+	status               int
+	written              int64
+	errorType            string
+	stream               *Stream
+}
+
+// NewResponseWriter returns a response writer for request r, which WriteResponse
+// uses to negotiate the response encoding from the Accept header, and WriteResponse
+// and WriteError use to compress bodies allowed by the Accept-Encoding header.
+// The request ID of r, if any, is set in the X-Request-ID response header.
+func NewResponseWriter(w http.ResponseWriter, r *http.Request, options ...WriteOption) http.ResponseWriter {
+	rw := &responseWriter{
+		ResponseWriter:       w,
+		request:              r,
+		compressionThreshold: DefaultCompressionThreshold,
+	}
+
+	for _, o := range options {
+		o(rw)
+	}
+
+	if id, ok := RequestIDFromContext(r.Context()); ok {
+		w.Header().Set(RequestIDHeader, id)
+	}
+
+	return rw
+}
+
+// WriteHeader implementation.
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implementation.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// Flush implementation.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// requestFromWriter returns the request of a response writer returned by NewResponseWriter.
+func requestFromWriter(w http.ResponseWriter) (*http.Request, bool) {
+	rw, ok := w.(*responseWriter)
+	if !ok {
+		return nil, false
+	}
+	return rw.request, true
+}
@@ -0,0 +1,38 @@
+// Package cbor provides an application/cbor codec, registered when imported.
+package cbor
+
+import (
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/apex/rpc"
+)
+
+// ContentType is the CBOR media type.
+const ContentType = "application/cbor"
+
+func init() {
+	rpc.RegisterCodec(Codec{})
+}
+
+// encMode encodes times as RFC 3339 strings, matching JSON.
+var encMode, _ = cbor.EncOptions{
+	Time: cbor.TimeRFC3339Nano,
+}.EncMode()
+
+// Codec is a CBOR codec, using json struct tags for field names.
+type Codec struct{}
+
+// ContentType implementation.
+func (Codec) ContentType() string {
+	return ContentType
+}
+
+// Marshal implementation.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return encMode.Marshal(v)
+}
+
+// Unmarshal implementation.
+func (Codec) Unmarshal(b []byte, v interface{}) error {
+	return cbor.Unmarshal(b, v)
+}
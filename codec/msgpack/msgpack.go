@@ -0,0 +1,41 @@
+// Package msgpack provides an application/msgpack codec, registered when imported.
+package msgpack
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/apex/rpc"
+)
+
+// ContentType is the MessagePack media type.
+const ContentType = "application/msgpack"
+
+func init() {
+	rpc.RegisterCodec(Codec{})
+}
+
+// Codec is a MessagePack codec, using json struct tags for field names.
+type Codec struct{}
+
+// ContentType implementation.
+func (Codec) ContentType() string {
+	return ContentType
+}
+
+// Marshal implementation.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+// Unmarshal implementation.
+func (Codec) Unmarshal(b []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(b))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,85 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+	"github.com/apex/rpc/codec/msgpack"
+)
+
+type pet struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func TestCodec(t *testing.T) {
+	in := pet{Name: "Tobi", CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	// response
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Accept", "application/msgpack, application/json;q=0.5")
+	w := httptest.NewRecorder()
+	rpc.WriteResponse(rpc.NewResponseWriter(w, r), in)
+	assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+
+	// request
+	r = httptest.NewRequest("POST", "/", bytes.NewReader(w.Body.Bytes()))
+	r.Header.Set("Content-Type", msgpack.ContentType)
+	var out pet
+	err := rpc.ReadRequest(r, &out)
+	assert.NoError(t, err, "reading")
+	assert.Equal(t, "Tobi", out.Name)
+	assert.True(t, in.CreatedAt.Equal(out.CreatedAt))
+}
+
+func TestCodec_malformed(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte{0xc1}))
+	r.Header.Set("Content-Type", msgpack.ContentType)
+	var out pet
+	err := rpc.ReadRequest(r, &out)
+	assert.EqualError(t, err, "Failed to parse malformed request body, must be a valid application/msgpack object")
+}
+
+func TestCodec_checked(t *testing.T) {
+	read := func(v interface{}, options ...rpc.ReadOption) error {
+		b, err := msgpack.Codec{}.Marshal(v)
+		assert.NoError(t, err, "marshaling")
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		r.Header.Set("Content-Type", msgpack.ContentType)
+		var out pet
+		return rpc.ReadRequest(r, &out, options...)
+	}
+
+	t.Run("with an unknown field", func(t *testing.T) {
+		err := read(map[string]interface{}{"nmae": "Tobi"}, rpc.Strict())
+		assert.EqualError(t, err, `Unknown field "nmae"`)
+	})
+
+	t.Run("with a mixed-case duplicate key", func(t *testing.T) {
+		err := read(map[string]interface{}{"name": "Tobi", "Name": "Loki"}, rpc.Strict())
+		assert.EqualError(t, err, `Duplicate field "name"`)
+	})
+
+	t.Run("with a string exceeding the length", func(t *testing.T) {
+		err := read(map[string]interface{}{"name": "Tobi"}, rpc.MaxStringLength(2))
+		assert.EqualError(t, err, `Field "name" exceeds the maximum string length of 2`)
+	})
+
+	t.Run("with a body exceeding the depth", func(t *testing.T) {
+		err := read(map[string]interface{}{"name": map[string]interface{}{}}, rpc.MaxDepth(1))
+		assert.EqualError(t, err, `Field "name" exceeds the maximum nesting depth of 1`)
+	})
+}
+
+func TestCodec_unsupported(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Content-Type", "text/plain")
+	var out pet
+	err := rpc.ReadRequest(r, &out)
+	assert.EqualError(t, err, `Unsupported request Content-Type, must be application/json or application/msgpack`)
+}
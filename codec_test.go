@@ -0,0 +1,59 @@
+package rpc_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test codec lookups.
+func TestLookupCodec(t *testing.T) {
+	t.Run("with parameters", func(t *testing.T) {
+		c, ok := rpc.LookupCodec("application/json; charset=utf-8")
+		assert.True(t, ok)
+		assert.Equal(t, "application/json", c.ContentType())
+	})
+
+	t.Run("with an unregistered media type", func(t *testing.T) {
+		_, ok := rpc.LookupCodec("text/plain")
+		assert.False(t, ok)
+	})
+
+	t.Run("with a malformed media type", func(t *testing.T) {
+		_, ok := rpc.LookupCodec("application/json;;")
+		assert.False(t, ok)
+	})
+}
+
+// Test response negotiation.
+func TestNewResponseWriter(t *testing.T) {
+	t.Run("without an Accept header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(rpc.NewResponseWriter(w, r), map[string]string{"name": "Tobi"})
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, "{\n  \"name\": \"Tobi\"\n}\n", w.Body.String())
+	})
+
+	t.Run("with an unsupported Accept header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept", "text/html, */*;q=0.8")
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(rpc.NewResponseWriter(w, r), map[string]string{"name": "Tobi"})
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	})
+
+	t.Run("with a charset parameter", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json; charset=utf-8")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.NoError(t, err, "parsing")
+		assert.Equal(t, "Tobi", in.Name)
+	})
+}
@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultCompressionThreshold is the default minimum size in bytes of compressed response bodies.
+const DefaultCompressionThreshold = 1024
+
+// DefaultMaxDecompressedBytes is the default limit of decompressed request bodies.
+const DefaultMaxDecompressedBytes = 32 << 20
+
+// encodings supported, in order of preference.
+var encodings = []string{"zstd", "gzip"}
+
+// zstdEncoder is shared, as EncodeAll is safe for concurrent use.
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+// WriteOption is a NewResponseWriter option.
+type WriteOption func(*responseWriter)
+
+// CompressionThreshold sets the minimum size in bytes of response bodies
+// compressed when the request's Accept-Encoding allows it, defaulting
+// to DefaultCompressionThreshold. Zero or less disables compression.
+func CompressionThreshold(n int) WriteOption {
+	return func(w *responseWriter) {
+		w.compressionThreshold = n
+	}
+}
+
+// acceptEncoding returns the supported encoding best matching
+// the Accept-Encoding header value s, or an empty string.
+func acceptEncoding(s string) string {
+	weights := map[string]float64{}
+	for _, part := range strings.Split(s, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+
+		weights[name] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, name := range encodings {
+		q, ok := weights[name]
+		if !ok {
+			q = weights["*"]
+		}
+
+		if q > bestQ {
+			best = name
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+// compress returns b compressed with encoding.
+func compress(encoding string, b []byte) ([]byte, error) {
+	switch encoding {
+	case "zstd":
+		return zstdEncoder.EncodeAll(b, nil), nil
+	default:
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(b); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// decompressor returns a reader decompressing r with the Content-Encoding
+// header value s, or false when the encoding is unsupported.
+func decompressor(r io.Reader, s string) (io.ReadCloser, bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r)
+		return gz, true, err
+	case "zstd":
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, true, err
+		}
+		return d.IOReadCloser(), true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// writeBody writes the response body b with status code, compressing it
+// when w was returned by NewResponseWriter and the request accepts it.
+func writeBody(w http.ResponseWriter, status int, contentType string, b []byte) {
+	w.Header().Set("Content-Type", contentType)
+
+	if rw, ok := w.(*responseWriter); ok && rw.compressionThreshold > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := acceptEncoding(rw.request.Header.Get("Accept-Encoding"))
+		if encoding != "" && len(b) >= rw.compressionThreshold {
+			if c, err := compress(encoding, b); err == nil {
+				w.Header().Set("Content-Encoding", encoding)
+				b = c
+			}
+		}
+	}
+
+	w.WriteHeader(status)
+	w.Write(b)
+}
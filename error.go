@@ -1,7 +1,11 @@
 package rpc
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // StatusProvider is the interface used for providing an HTTP status code.
@@ -14,11 +18,51 @@ type TypeProvider interface {
 	Type() string
 }
 
-// ServerError is a server error which implements StatusProvider and TypeProvider.
+// DetailsProvider is the interface used for providing structured error details.
+type DetailsProvider interface {
+	Details() map[string]interface{}
+}
+
+// RetryAfterProvider is the interface used for providing the duration
+// after which a request may be retried.
+type RetryAfterProvider interface {
+	RetryAfter() time.Duration
+}
+
+// ServerError is a server error which implements StatusProvider, TypeProvider
+// and DetailsProvider. The wrapped cause is available via errors.Unwrap, but
+// is never written in responses. ServerError values are comparable, so they
+// may be used as sentinel errors.
 type ServerError struct {
-	status  int
-	kind    string
-	message string
+	status     int
+	kind       string
+	message    string
+	cause      error
+	details    *errorDetails
+	retryAfter time.Duration
+}
+
+// errorDetails are the details of a ServerError, behind a pointer
+// so that the map does not make ServerError values incomparable.
+type errorDetails struct {
+	m map[string]interface{}
+}
+
+// ErrorOption is a ServerError option.
+type ErrorOption func(*ServerError)
+
+// WithCause sets the wrapped cause of the error.
+func WithCause(err error) ErrorOption {
+	return func(e *ServerError) {
+		e.cause = err
+	}
+}
+
+// WithDetails sets the structured details written in the error response.
+func WithDetails(details map[string]interface{}) ErrorOption {
+	return func(e *ServerError) {
+		e.details = &errorDetails{m: details}
+	}
 }
 
 // StatusCode implementation.
@@ -31,18 +75,86 @@ func (e ServerError) Type() string {
 	return e.kind
 }
 
+// WithRetryAfter sets the duration after which the request may be retried,
+// written in the Retry-After header of the response.
+func WithRetryAfter(d time.Duration) ErrorOption {
+	return func(e *ServerError) {
+		e.retryAfter = d
+	}
+}
+
+// Details implementation.
+func (e ServerError) Details() map[string]interface{} {
+	if e.details == nil {
+		return nil
+	}
+	return e.details.m
+}
+
+// RetryAfter implementation.
+func (e ServerError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
 // Error implementation.
 func (e ServerError) Error() string {
 	return e.message
 }
 
+// Unwrap returns the wrapped cause, if any.
+func (e ServerError) Unwrap() error {
+	return e.cause
+}
+
+// Is returns true if target is a ServerError with the same status code and type,
+// allowing errors.Is to match errors regardless of their message.
+func (e ServerError) Is(target error) bool {
+	t, ok := target.(ServerError)
+	return ok && t.status == e.status && t.kind == e.kind
+}
+
 // Error returns a new ServerError with HTTP status code, kind and message.
-func Error(status int, kind, message string) error {
-	return ServerError{
+func Error(status int, kind, message string, options ...ErrorOption) error {
+	e := ServerError{
 		kind:    kind,
 		status:  status,
 		message: message,
 	}
+
+	for _, o := range options {
+		o(&e)
+	}
+
+	return e
+}
+
+// Wrap returns a new ServerError with HTTP status code, kind and message, wrapping err.
+func Wrap(err error, status int, kind, message string) error {
+	return Error(status, kind, message, WithCause(err))
+}
+
+// ErrMethodNotFound is the cause of ErrInvalidMethod, matched with errors.Is
+// to detect calls of methods which do not exist, as ServerError values of the
+// same status and type match each other.
+var ErrMethodNotFound = errors.New("method not found")
+
+// ErrInvalidMethod is the error of generated servers for calls of methods which do not exist.
+var ErrInvalidMethod = Wrap(ErrMethodNotFound, http.StatusBadRequest, "bad_request", "Invalid method")
+
+// OnError, when non-nil, is called by WriteError with each error before it
+// is written, including the wrapped causes which are never written, and
+// is useful for logging. The request is nil unless the response writer
+// was returned by NewResponseWriter.
+var OnError func(r *http.Request, err error)
+
+// Redact, when non-nil, returns the message written by WriteError in place
+// of the message of errors with a 5xx status code, preventing internal
+// details from leaking to clients.
+var Redact func(status int, err error) string
+
+// RedactStatusText returns the status text of the status code, and may be assigned to Redact.
+func RedactStatusText(status int, err error) string {
+	return http.StatusText(status)
 }
 
 // BadRequest returns a new bad request error.
@@ -55,42 +167,176 @@ func Invalid(message string) error {
 	return Error(http.StatusBadRequest, "invalid", message)
 }
 
-// serverErrorResponse is an error response.
-type serverErrorResponse struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
+// NotFound returns a new not found error.
+func NotFound(message string) error {
+	return Error(http.StatusNotFound, "not_found", message)
+}
+
+// Unauthorized returns a new unauthorized error, for requests lacking valid credentials.
+func Unauthorized(message string) error {
+	return Error(http.StatusUnauthorized, "unauthorized", message)
+}
+
+// Forbidden returns a new forbidden error, for requests with credentials lacking permission.
+func Forbidden(message string) error {
+	return Error(http.StatusForbidden, "forbidden", message)
+}
+
+// Conflict returns a new conflict error.
+func Conflict(message string) error {
+	return Error(http.StatusConflict, "conflict", message)
+}
+
+// PreconditionFailed returns a new precondition failed error.
+func PreconditionFailed(message string) error {
+	return Error(http.StatusPreconditionFailed, "precondition_failed", message)
+}
+
+// RateLimited returns a new rate limited error, which may be retried after d.
+func RateLimited(message string, d time.Duration) error {
+	return Error(http.StatusTooManyRequests, "rate_limited", message, WithRetryAfter(d))
+}
+
+// Unavailable returns a new service unavailable error.
+func Unavailable(message string) error {
+	return Error(http.StatusServiceUnavailable, "unavailable", message)
+}
+
+// Internal returns a new internal server error.
+func Internal(message string) error {
+	return Error(http.StatusInternalServerError, "internal", message)
+}
+
+// ErrorResponse is the body of error responses written by WriteError.
+type ErrorResponse struct {
+	Type      string                 `json:"type"`
+	Message   string                 `json:"message"`
+	Fields    []ValidationError      `json:"fields,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
 }
 
 // WriteError writes an error.
 //
-// If err is a StatusProvider the status code provided
+// If err is, or wraps, a StatusProvider the status code provided
 // is used, otherwise it defaults to StatusInternalServerError.
 //
-// If err is a TypeProvider the type provided is used,
+// If err is, or wraps, a TypeProvider the type provided is used,
 // otherwise it defaults to "internal".
 //
-// The message in the response uses the Error()
-// implementation.
+// If err is, or wraps, a FieldsProvider the field validation errors
+// are included in the response as "fields", and likewise the details
+// of a DetailsProvider are included as "details". The Retry-After header
+// is set for a RetryAfterProvider, and the request ID is included
+// as "request_id" when the response writer was returned by
+// NewResponseWriter for a request with an ID. An *http.MaxBytesError
+// of a body limited by http.MaxBytesReader is written as a 413 error.
 //
+// The message in the response uses the Error() implementation of
+// the provider, so that the messages of errors wrapping it are not
+// exposed, otherwise the Error() implementation of err. Messages of
+// 5xx errors are replaced using Redact when set.
 func WriteError(w http.ResponseWriter, err error) {
-	w.Header().Set("Content-Type", "application/json")
+	r, _ := requestFromWriter(w)
+	if OnError != nil {
+		OnError(r, err)
+	}
 
-	if e, ok := err.(StatusProvider); ok {
-		w.WriteHeader(e.StatusCode())
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
+	recordError(w, err)
+
+	var rp RetryAfterProvider
+	if errors.As(err, &rp) && rp.RetryAfter() > 0 {
+		seconds := math.Ceil(rp.RetryAfter().Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+
+	status, body := errorResponse(r, err)
+
+	if rw, ok := w.(*responseWriter); ok {
+		rw.errorType = body.Type
+
+		// report errors of started streams as the final message
+		if rw.stream != nil && rw.stream.Started() {
+			rw.stream.writeError(status, body)
+			return
+		}
+	}
+
+	b, _ := json.MarshalIndent(body, "", "  ")
+	writeBody(w, status, "application/json", append(b, '\n'))
+}
+
+// errorRecorder is the interface used by response writers recording the errors written by WriteError.
+type errorRecorder interface {
+	recordError(err error)
+}
+
+// recordError records err in w, or the response writer it wraps, if it is an errorRecorder.
+func recordError(w http.ResponseWriter, err error) {
+	for {
+		if r, ok := w.(errorRecorder); ok {
+			r.recordError(err)
+			return
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = u.Unwrap()
+	}
+}
+
+// errorResponse returns the status code and response body of err
+// in the handling of request r, which may be nil.
+func errorResponse(r *http.Request, err error) (int, ErrorResponse) {
+	// bodies limited by http.MaxBytesReader
+	var sp StatusProvider
+	var mbe *http.MaxBytesError
+	if !errors.As(err, &sp) && errors.As(err, &mbe) {
+		err = tooLarge(mbe.Limit)
+	}
+
+	status := http.StatusInternalServerError
+	message := err.Error()
+
+	if errors.As(err, &sp) {
+		status = sp.StatusCode()
+		if e, ok := sp.(error); ok {
+			message = e.Error()
+		}
 	}
 
-	var body serverErrorResponse
+	var body ErrorResponse
 
-	if e, ok := err.(TypeProvider); ok {
-		body.Type = e.Type()
+	var tp TypeProvider
+	if errors.As(err, &tp) {
+		body.Type = tp.Type()
+		if e, ok := tp.(error); ok && sp == nil {
+			message = e.Error()
+		}
 	} else {
 		body.Type = "internal"
 	}
 
-	body.Message = err.Error()
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	enc.Encode(body)
+	var fp FieldsProvider
+	if errors.As(err, &fp) {
+		body.Fields = fp.Fields()
+	}
+
+	var dp DetailsProvider
+	if errors.As(err, &dp) {
+		body.Details = dp.Details()
+	}
+
+	if status >= 500 && Redact != nil {
+		message = Redact(status, err)
+	}
+
+	if r != nil {
+		body.RequestID, _ = RequestIDFromContext(r.Context())
+	}
+
+	body.Message = message
+	return status, body
 }
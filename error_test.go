@@ -2,9 +2,12 @@ package rpc_test
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/tj/assert"
 
@@ -34,4 +37,116 @@ func TestWriteError(t *testing.T) {
 		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 		assert.Equal(t, 400, w.Code)
 	})
+
+	t.Run("with a MaxBytesError", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, fmt.Errorf("reading: %w", &http.MaxBytesError{Limit: 10}))
+		assert.Equal(t, 413, w.Code)
+		assert.Equal(t, "{\n  \"type\": \"request_too_large\",\n  \"message\": \"Request body must not exceed 10 bytes\"\n}", strings.TrimSpace(w.Body.String()))
+	})
+}
+
+// Test wrapped errors.
+func TestWriteError_wrapped(t *testing.T) {
+	t.Run("with a wrapped ServerError", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, fmt.Errorf("fetching user 5 from db: %w", rpc.Error(404, "not_found", "User not found")))
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, "{\n  \"type\": \"not_found\",\n  \"message\": \"User not found\"\n}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with a cause", func(t *testing.T) {
+		cause := errors.New("connection refused")
+		err := rpc.Wrap(cause, 503, "unavailable", "Service unavailable")
+		assert.True(t, errors.Is(err, cause))
+
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, err)
+		assert.Equal(t, 503, w.Code)
+		assert.Equal(t, "{\n  \"type\": \"unavailable\",\n  \"message\": \"Service unavailable\"\n}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with details", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, rpc.Error(409, "conflict", "Name taken", rpc.WithDetails(map[string]interface{}{"name": "tobi"})))
+		assert.Equal(t, "{\n  \"type\": \"conflict\",\n  \"message\": \"Name taken\",\n  \"details\": {\n    \"name\": \"tobi\"\n  }\n}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with errors.Is", func(t *testing.T) {
+		err := fmt.Errorf("loading: %w", rpc.Error(404, "not_found", "Pet not found"))
+		assert.True(t, errors.Is(err, rpc.Error(404, "not_found", "")))
+		assert.False(t, errors.Is(err, rpc.Error(400, "bad_request", "")))
+	})
+
+	t.Run("with comparable errors", func(t *testing.T) {
+		details := rpc.WithDetails(map[string]interface{}{"name": "tobi"})
+		a := rpc.Error(409, "conflict", "Name taken", details)
+		b := rpc.Error(409, "conflict", "Name taken", details)
+		assert.True(t, a == a)
+		assert.False(t, a == b)
+		assert.True(t, rpc.NotFound("Pet not found") == rpc.NotFound("Pet not found"))
+		assert.Equal(t, a.(rpc.ServerError).Details(), b.(rpc.ServerError).Details())
+	})
+}
+
+// Test error hooks.
+func TestWriteError_hooks(t *testing.T) {
+	var logged []error
+	rpc.OnError = func(r *http.Request, err error) {
+		logged = append(logged, err)
+	}
+	rpc.Redact = rpc.RedactStatusText
+	defer func() {
+		rpc.OnError = nil
+		rpc.Redact = nil
+	}()
+
+	t.Run("with a 5xx error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, errors.New("pq: password authentication failed"))
+		assert.Equal(t, 500, w.Code)
+		assert.Equal(t, "{\n  \"type\": \"internal\",\n  \"message\": \"Internal Server Error\"\n}", strings.TrimSpace(w.Body.String()))
+		assert.EqualError(t, logged[0], "pq: password authentication failed")
+	})
+
+	t.Run("with a 4xx error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, rpc.BadRequest("Invalid method"))
+		assert.Contains(t, w.Body.String(), "Invalid method")
+	})
+}
+
+// Test the error catalog.
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		kind   string
+	}{
+		{rpc.BadRequest("Invalid method"), 400, "bad_request"},
+		{rpc.Invalid("Name is required"), 400, "invalid"},
+		{rpc.Unauthorized("Invalid token"), 401, "unauthorized"},
+		{rpc.Forbidden("Team access denied"), 403, "forbidden"},
+		{rpc.NotFound("Pet not found"), 404, "not_found"},
+		{rpc.Conflict("Pet already exists"), 409, "conflict"},
+		{rpc.PreconditionFailed("Pet was modified"), 412, "precondition_failed"},
+		{rpc.RateLimited("Slow down", time.Minute), 429, "rate_limited"},
+		{rpc.Internal("Something went wrong"), 500, "internal"},
+		{rpc.Unavailable("Try again later"), 503, "unavailable"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.kind, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rpc.WriteError(w, c.err)
+			assert.Equal(t, c.status, w.Code)
+			assert.Contains(t, w.Body.String(), fmt.Sprintf(`"type": %q`, c.kind))
+		})
+	}
+
+	t.Run("with a retry after duration", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, rpc.RateLimited("Slow down", 1500*time.Millisecond))
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	})
 }
@@ -10,7 +10,10 @@ import (
 
 var namespace = `using System;
 using System.Collections.Generic;
+using System.IO;
+using System.IO.Compression;
 using System.Net.Http;
+using System.Text;
 using System.Threading.Tasks;
 using Newtonsoft.Json;
 
@@ -20,23 +23,42 @@ namespace %s
 	{
 		class ApexLogsException : Exception
 		{
-			public ApexLogsException(int status) : base($"{status} response") 
-			{ }
+			public string RequestId { get; }
 
-			public ApexLogsException(int status, string type, string message) 
+			public ApexLogsException(int status, string requestId) : base($"{status} response") 
+			{
+				RequestId = requestId;
+			}
+
+			public ApexLogsException(int status, string type, string message, string requestId) 
 				: base($"{status} response: ${type}: {message}") 
-			{ }
+			{
+				RequestId = requestId;
+			}
 		}
 
 		private readonly string _url;
 		private readonly string _authToken;
 		private readonly HttpClient _httpClient;
+		private readonly int _compressionThreshold;
+		private string _traceParent;
+		private string _traceState;
 
-		public Client(HttpClient httpClient, string url, string authToken)
+		public Client(HttpClient httpClient, string url, string authToken, int compressionThreshold = 0)
 		{
 			_httpClient = httpClient;
 			_url = url;
 			_authToken = authToken;
+			_compressionThreshold = compressionThreshold;
+		}
+
+		/// Returns a copy of the client forwarding the W3C traceparent and tracestate header values.
+		public %s WithTrace(string traceParent, string traceState = null)
+		{
+			var client = (%s) MemberwiseClone();
+			client._traceParent = traceParent;
+			client._traceState = traceState;
+			return client;
 		}
 `
 
@@ -49,12 +71,23 @@ var call = `
 				Method = HttpMethod.Post,
 				RequestUri = new Uri(url)
 			};
+			var requestId = Guid.NewGuid().ToString("N");
 			message.Headers.Add("Content-Type", "application/json");
+			message.Headers.Add("X-Request-ID", requestId);
+			if (!string.IsNullOrWhiteSpace(_traceParent))
+				message.Headers.Add("traceparent", _traceParent);
+			if (!string.IsNullOrWhiteSpace(_traceState))
+				message.Headers.Add("tracestate", _traceState);
 			if (!string.IsNullOrWhiteSpace(_authToken))
 				message.Headers.Add("Authorization", $"Bearer {_authToken}");
 
 			if (parameters != null)
-				message.Content = new StringContent(JsonConvert.SerializeObject(parameters));
+			{
+				var json = JsonConvert.SerializeObject(parameters);
+				message.Content = _compressionThreshold > 0 && Encoding.UTF8.GetByteCount(json) >= _compressionThreshold
+					? Compress(json)
+					: new StringContent(json);
+			}
 
 			var response = await _httpClient.SendAsync(message);
 			var statusCode = (int) response.StatusCode;
@@ -62,10 +95,27 @@ var call = `
 
 			if (statusCode < 300) return content;
 
-			var body = JsonConvert.DeserializeObject<Dictionary<string, string>>(content)
-				?? throw new ApexLogsException(statusCode);
+			if (response.Headers.TryGetValues("X-Request-ID", out var values))
+				requestId = string.Join(",", values);
+
+			var body = JsonConvert.DeserializeObject<Dictionary<string, object>>(content)
+				?? throw new ApexLogsException(statusCode, requestId);
+
+			throw new ApexLogsException(statusCode, body["type"]?.ToString(), body["message"]?.ToString(), requestId);
+		}
+
+		private static HttpContent Compress(string json)
+		{
+			var output = new MemoryStream();
+			using (var gzip = new GZipStream(output, CompressionMode.Compress))
+			{
+				var bytes = Encoding.UTF8.GetBytes(json);
+				gzip.Write(bytes, 0, bytes.Length);
+			}
 
-			throw new ApexLogsException(statusCode, body["type"], body["message"]);
+			var content = new ByteArrayContent(output.ToArray());
+			content.Headers.ContentEncoding.Add("gzip");
+			return content;
 		}
 `
 
@@ -77,7 +127,7 @@ var closeNamespace = `	}
 func Generate(w io.Writer, s *schema.Schema, namespaceName, className string) error {
 	out := fmt.Fprintf
 
-	out(w, namespace, namespaceName, className)
+	out(w, namespace, namespaceName, className, className, className)
 
 	var indentDeclaration = "		"
 	var indentContent = "			"
@@ -1,6 +1,9 @@
 using System;
 using System.Collections.Generic;
+using System.IO;
+using System.IO.Compression;
 using System.Net.Http;
+using System.Text;
 using System.Threading.Tasks;
 using Newtonsoft.Json;
 
@@ -10,23 +13,42 @@ namespace ApexLogs
 	{
 		class ApexLogsException : Exception
 		{
-			public ApexLogsException(int status) : base($"{status} response") 
-			{ }
+			public string RequestId { get; }
 
-			public ApexLogsException(int status, string type, string message) 
+			public ApexLogsException(int status, string requestId) : base($"{status} response") 
+			{
+				RequestId = requestId;
+			}
+
+			public ApexLogsException(int status, string type, string message, string requestId) 
 				: base($"{status} response: ${type}: {message}") 
-			{ }
+			{
+				RequestId = requestId;
+			}
 		}
 
 		private readonly string _url;
 		private readonly string _authToken;
 		private readonly HttpClient _httpClient;
+		private readonly int _compressionThreshold;
+		private string _traceParent;
+		private string _traceState;
 
-		public Client(HttpClient httpClient, string url, string authToken)
+		public Client(HttpClient httpClient, string url, string authToken, int compressionThreshold = 0)
 		{
 			_httpClient = httpClient;
 			_url = url;
 			_authToken = authToken;
+			_compressionThreshold = compressionThreshold;
+		}
+
+		/// Returns a copy of the client forwarding the W3C traceparent and tracestate header values.
+		public Client WithTrace(string traceParent, string traceState = null)
+		{
+			var client = (Client) MemberwiseClone();
+			client._traceParent = traceParent;
+			client._traceState = traceState;
+			return client;
 		}
 
 		/// adds an item to the list.
@@ -59,12 +81,23 @@ namespace ApexLogs
 				Method = HttpMethod.Post,
 				RequestUri = new Uri(url)
 			};
+			var requestId = Guid.NewGuid().ToString("N");
 			message.Headers.Add("Content-Type", "application/json");
+			message.Headers.Add("X-Request-ID", requestId);
+			if (!string.IsNullOrWhiteSpace(_traceParent))
+				message.Headers.Add("traceparent", _traceParent);
+			if (!string.IsNullOrWhiteSpace(_traceState))
+				message.Headers.Add("tracestate", _traceState);
 			if (!string.IsNullOrWhiteSpace(_authToken))
 				message.Headers.Add("Authorization", $"Bearer {_authToken}");
 
 			if (parameters != null)
-				message.Content = new StringContent(JsonConvert.SerializeObject(parameters));
+			{
+				var json = JsonConvert.SerializeObject(parameters);
+				message.Content = _compressionThreshold > 0 && Encoding.UTF8.GetByteCount(json) >= _compressionThreshold
+					? Compress(json)
+					: new StringContent(json);
+			}
 
 			var response = await _httpClient.SendAsync(message);
 			var statusCode = (int) response.StatusCode;
@@ -72,10 +105,27 @@ namespace ApexLogs
 
 			if (statusCode < 300) return content;
 
-			var body = JsonConvert.DeserializeObject<Dictionary<string, string>>(content)
-				?? throw new ApexLogsException(statusCode);
+			if (response.Headers.TryGetValues("X-Request-ID", out var values))
+				requestId = string.Join(",", values);
+
+			var body = JsonConvert.DeserializeObject<Dictionary<string, object>>(content)
+				?? throw new ApexLogsException(statusCode, requestId);
+
+			throw new ApexLogsException(statusCode, body["type"]?.ToString(), body["message"]?.ToString(), requestId);
+		}
+
+		private static HttpContent Compress(string json)
+		{
+			var output = new MemoryStream();
+			using (var gzip = new GZipStream(output, CompressionMode.Compress))
+			{
+				var bytes = Encoding.UTF8.GetBytes(json);
+				gzip.Write(bytes, 0, bytes.Length);
+			}
 
-			throw new ApexLogsException(statusCode, body["type"], body["message"]);
+			var content = new ByteArrayContent(output.ToArray());
+			content.Headers.ContentEncoding.Add("gzip");
+			return content;
 		}
 	}
 }
@@ -23,6 +23,7 @@ func Generate(w io.Writer, s *schema.Schema) error {
 	generateTypes(w, s)
 	generateMethodTypes(w, s)
 	generateMethodFuncs(w, s)
+	generateHeaderFuncs(w)
 	generateDecoderFuncs(w, s)
 	return nil
 }
@@ -75,6 +76,28 @@ func generateMethodFuncs(w io.Writer, s *schema.Schema) {
 	}
 }
 
+// headers are the request header functions.
+var headers = `-- HEADERS
+
+{-| traceHeaders returns the headers forwarding the W3C traceparent and tracestate values. -}
+traceHeaders : String -> Maybe String -> List Http.Header
+traceHeaders traceparent tracestate =
+    case tracestate of
+        Just state ->
+            [ Http.header "traceparent" traceparent, Http.header "tracestate" state ]
+
+        Nothing ->
+            [ Http.header "traceparent" traceparent ]
+
+
+`
+
+// generateHeaderFuncs writes request header functions to w, used until the
+// method functions perform requests, as they do in the other clients.
+func generateHeaderFuncs(w io.Writer) {
+	fmt.Fprint(w, headers)
+}
+
 // generateDecoderFuncs writes json decoder functions to w.
 func generateDecoderFuncs(w io.Writer, s *schema.Schema) {
 	out := fmt.Fprintf
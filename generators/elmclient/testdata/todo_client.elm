@@ -46,6 +46,19 @@ removeItem : RemoveItemInput
 removeItem = 
    ...
 
+-- HEADERS
+
+{-| traceHeaders returns the headers forwarding the W3C traceparent and tracestate values. -}
+traceHeaders : String -> Maybe String -> List Http.Header
+traceHeaders traceparent tracestate =
+    case tracestate of
+        Just state ->
+            [ Http.header "traceparent" traceparent, Http.header "tracestate" state ]
+
+        Nothing ->
+            [ Http.header "traceparent" traceparent ]
+
+
 -- DECODERS
 
 itemDecoder : Decoder Item
@@ -3,7 +3,9 @@ package goclient
 import (
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/apex/rpc/internal/catalog"
 	"github.com/apex/rpc/internal/format"
 	"github.com/apex/rpc/schema"
 )
@@ -14,6 +16,16 @@ type Error struct {
 	StatusCode int
 	Type       string
 	Message    string
+	Fields     []FieldError
+	Details    map[string]interface{}
+	RetryAfter time.Duration
+	RequestID  string
+}
+
+// FieldError is a field validation error, with the JSON path of the field.
+type FieldError struct {
+	Field   string
+	Message string
 }
 
 // Error implementation.
@@ -24,68 +36,480 @@ func (e Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Type, e.Message)
 }
 
+// Codec is the interface used for encoding request bodies and decoding response bodies.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
 // call implementation.
-func call(client *http.Client, authToken, endpoint, method string, in, out interface{}) error {
+func (c *Client) call(method string, in, out interface{}) error {
+	res, err := c.do(method, in, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// output params
+	if out != nil && c.Codec != nil {
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		return c.Codec.Unmarshal(b, out)
+	}
+
+	if out != nil {
+		err = json.NewDecoder(res.Body).Decode(out)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// do sends a request for method with input in, returning the response, or an Error
+// for error responses. The Accept header defaults to the request content type.
+func (c *Client) do(method string, in interface{}, accept string) (*http.Response, error) {
 	var body io.Reader
 
 	// default client
+	client := c.HTTPClient
 	if client == nil {
 		client = http.DefaultClient
 	}
 
+	// content type
+	contentType := "application/json"
+	if c.Codec != nil {
+		contentType = c.Codec.ContentType()
+	}
+
 	// input params
+	var encoding string
 	if in != nil {
-		var buf bytes.Buffer
-		err := json.NewEncoder(&buf).Encode(in)
+		var b []byte
+		var err error
+		if c.Codec != nil {
+			b, err = c.Codec.Marshal(in)
+		} else {
+			b, err = json.Marshal(in)
+		}
 		if err != nil {
-			return fmt.Errorf("encoding: %w", err)
+			return nil, fmt.Errorf("encoding: %w", err)
 		}
-		body = &buf
+
+		// compression
+		if c.CompressionThreshold > 0 && len(b) >= c.CompressionThreshold {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write(b)
+			if err := gz.Close(); err != nil {
+				return nil, fmt.Errorf("compressing: %w", err)
+			}
+			b = buf.Bytes()
+			encoding = "gzip"
+		}
+
+		body = bytes.NewReader(b)
 	}
 
 	// POST request
-	req, err := http.NewRequest("POST", endpoint+"/"+method, body)
+	req, err := http.NewRequest("POST", c.URL+"/"+method, body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if accept == "" {
+		accept = contentType
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", accept)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+
+	// request id
+	requestID := newRequestID()
+	req.Header.Set("X-Request-ID", requestID)
+
+	// trace context
+	if c.TraceParent != "" {
+		req.Header.Set("traceparent", c.TraceParent)
+		if c.TraceState != "" {
+			req.Header.Set("tracestate", c.TraceState)
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
 
 	// auth token
-	if authToken != "" {
-		req.Header.Set("Authorization", "Bearer "+authToken)
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AuthToken)
 	}
 
 	// response
 	res, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer res.Body.Close()
 
 	// error
 	if res.StatusCode >= 300 {
+		defer res.Body.Close()
 		var e Error
 		if res.Header.Get("Content-Type") == "application/json" {
 			if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
-				return err
+				return nil, err
 			}
 		}
 		e.Status = http.StatusText(res.StatusCode)
 		e.StatusCode = res.StatusCode
-		return e
+		e.RequestID = requestID
+		if id := res.Header.Get("X-Request-ID"); id != "" {
+			e.RequestID = id
+		}
+		if n, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			e.RetryAfter = time.Duration(n) * time.Second
+		}
+		return nil, e
 	}
 
-	// output params
-	if out != nil {
-		err = json.NewDecoder(res.Body).Decode(out)
-		if err != nil {
-			return err
+	return res, nil
+}
+
+// errorResponse is an error response body.
+type errorResponse struct {
+	Type      string                 ` + "`json:\"type\"`" + `
+	Message   string                 ` + "`json:\"message\"`" + `
+	Fields    []FieldError           ` + "`json:\"fields\"`" + `
+	Details   map[string]interface{} ` + "`json:\"details\"`" + `
+	RequestID string                 ` + "`json:\"request_id\"`" + `
+}
+
+// toError returns an Error with status code.
+func (e errorResponse) toError(status int) Error {
+	return Error{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Type:       e.Type,
+		Message:    e.Message,
+		Fields:     e.Fields,
+		Details:    e.Details,
+		RequestID:  e.RequestID,
+	}
+}`
+
+var batch = `// BatchCall is a call in a batch, with its output and error set by Batch.Send.
+type BatchCall struct {
+	// Err is the error of the call, if any.
+	Err error
+
+	method string
+	in     interface{}
+	out    interface{}
+}
+
+// add adds a call to the batch.
+func (b *Batch) add(method string, in, out interface{}) *BatchCall {
+	call := &BatchCall{
+		method: method,
+		in:     in,
+		out:    out,
+	}
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// Send sends the calls of the batch in a single request, setting their outputs
+// and errors. The error returned is the error of the batch request itself.
+func (b *Batch) Send() error {
+	type batchCall struct {
+		Method string      ` + "`json:\"method\"`" + `
+		Input  interface{} ` + "`json:\"input,omitempty\"`" + `
+	}
+
+	type batchResult struct {
+		Status int             ` + "`json:\"status\"`" + `
+		Result json.RawMessage ` + "`json:\"result\"`" + `
+		Error  *errorResponse  ` + "`json:\"error\"`" + `
+	}
+
+	if len(b.calls) == 0 {
+		return nil
+	}
+
+	calls := make([]batchCall, len(b.calls))
+	for i, c := range b.calls {
+		calls[i] = batchCall{
+			Method: c.method,
+			Input:  c.in,
+		}
+	}
+
+	// batches are always JSON
+	client := *b.client
+	client.Codec = nil
+
+	var results []batchResult
+	err := client.call("_batch", calls, &results)
+	if err != nil {
+		return err
+	}
+
+	if len(results) != len(b.calls) {
+		return fmt.Errorf("batch: expected %d results, got %d", len(b.calls), len(results))
+	}
+
+	for i, r := range results {
+		c := b.calls[i]
+
+		if r.Error != nil {
+			c.Err = r.Error.toError(r.Status)
+			continue
+		}
+
+		if c.out != nil && len(r.Result) > 0 {
+			c.Err = json.Unmarshal(r.Result, c.out)
 		}
 	}
 
 	return nil
 }`
 
+var stream = `// stream is a newline-delimited JSON response stream.
+type stream struct {
+	res *http.Response
+	dec *json.Decoder
+	err error
+}
+
+// newStream returns a new stream of response res.
+func newStream(res *http.Response) *stream {
+	return &stream{
+		res: res,
+		dec: json.NewDecoder(res.Body),
+	}
+}
+
+// next decodes the next output into out, returning false at the end of the stream or on error.
+func (s *stream) next(out interface{}) bool {
+	if s.err != nil {
+		return false
+	}
+
+	var msg struct {
+		Status int             ` + "`json:\"status\"`" + `
+		Result json.RawMessage ` + "`json:\"result\"`" + `
+		Error  *errorResponse  ` + "`json:\"error\"`" + `
+	}
+
+	err := s.dec.Decode(&msg)
+	if err == io.EOF {
+		return false
+	}
+
+	if err != nil {
+		s.err = err
+		return false
+	}
+
+	if msg.Error != nil {
+		s.err = msg.Error.toError(msg.Status)
+		return false
+	}
+
+	s.err = json.Unmarshal(msg.Result, out)
+	return s.err == nil
+}
+
+// Err returns the error which ended the stream, if any.
+func (s *stream) Err() error {
+	return s.err
+}
+
+// Close closes the stream.
+func (s *stream) Close() error {
+	return s.res.Body.Close()
+}`
+
+var conn = `// ErrClosed is returned by calls of a closed connection.
+var ErrClosed = errors.New("connection closed")
+
+// Notification is a notification sent by the server over a connection.
+type Notification struct {
+	Event string
+	Data  json.RawMessage
+}
+
+// Conn is a WebSocket connection multiplexing calls, see Client.Connect.
+type Conn struct {
+	conn    *websocket.Conn
+	notify  func(Notification)
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	id      uint64
+	pending map[uint64]chan connMessage
+	err     error
+	done    chan struct{}
+}
+
+// connCall is a call sent over a connection.
+type connCall struct {
+	ID     uint64      ` + "`json:\"id\"`" + `
+	Method string      ` + "`json:\"method\"`" + `
+	Input  interface{} ` + "`json:\"input,omitempty\"`" + `
+}
+
+// connMessage is a result or notification received over a connection.
+type connMessage struct {
+	ID     uint64          ` + "`json:\"id\"`" + `
+	Status int             ` + "`json:\"status\"`" + `
+	Result json.RawMessage ` + "`json:\"result\"`" + `
+	Error  *errorResponse  ` + "`json:\"error\"`" + `
+	Event  string          ` + "`json:\"event\"`" + `
+	Data   json.RawMessage ` + "`json:\"data\"`" + `
+}
+
+// Connect opens a WebSocket connection to the API, which requires the server to
+// enable WebSockets. Notifications are passed to notify when non-nil, which is
+// called from the connection's read loop and must not block.
+func (c *Client) Connect(ctx context.Context, notify func(Notification)) (*Conn, error) {
+	header := http.Header{}
+
+	// trace context
+	if c.TraceParent != "" {
+		header.Set("traceparent", c.TraceParent)
+		if c.TraceState != "" {
+			header.Set("tracestate", c.TraceState)
+		}
+	}
+
+	// auth token
+	if c.AuthToken != "" {
+		header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+
+	url := "ws" + strings.TrimPrefix(c.URL, "http") + "/_websocket"
+	ws, res, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		if res != nil && res.StatusCode >= 300 {
+			return nil, Error{
+				Status:     http.StatusText(res.StatusCode),
+				StatusCode: res.StatusCode,
+			}
+		}
+		return nil, err
+	}
+
+	conn := &Conn{
+		conn:    ws,
+		notify:  notify,
+		pending: make(map[uint64]chan connMessage),
+		done:    make(chan struct{}),
+	}
+
+	go conn.read()
+	return conn, nil
+}
+
+// read reads messages until the connection is closed.
+func (c *Conn) read() {
+	for {
+		var msg connMessage
+		err := c.conn.ReadJSON(&msg)
+		if err != nil {
+			c.mu.Lock()
+			if c.err == nil {
+				c.err = err
+			}
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+
+		// notification
+		if msg.Event != "" {
+			if c.notify != nil {
+				c.notify(Notification{Event: msg.Event, Data: msg.Data})
+			}
+			continue
+		}
+
+		// result
+		c.mu.Lock()
+		ch, ok := c.pending[msg.ID]
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+
+		if ok {
+			ch <- msg
+		}
+	}
+}
+
+// call implementation.
+func (c *Conn) call(method string, in, out interface{}) error {
+	ch := make(chan connMessage, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.id++
+	id := c.id
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(connCall{ID: id, Method: method, Input: in})
+	c.writeMu.Unlock()
+
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error.toError(msg.Status)
+		}
+
+		if out != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, out)
+		}
+
+		return nil
+	case <-c.done:
+		return c.Err()
+	}
+}
+
+// Done returns a channel closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error which closed the connection, if any.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	c.mu.Unlock()
+	return c.conn.Close()
+}`
+
 // Generate writes the Go client implementations to w.
 func Generate(w io.Writer, s *schema.Schema) error {
 	out := fmt.Fprintf
@@ -97,50 +521,216 @@ func Generate(w io.Writer, s *schema.Schema) error {
 	out(w, "  // AuthToken is an optional authentication token.\n")
 	out(w, "  AuthToken string\n\n")
 	out(w, "  // HTTPClient is the client used for making requests, defaulting to http.DefaultClient.\n")
-	out(w, "  HTTPClient *http.Client\n")
+	out(w, "  HTTPClient *http.Client\n\n")
+	out(w, "  // Codec is an optional codec used for request and response bodies, defaulting to JSON.\n")
+	out(w, "  Codec Codec\n\n")
+	out(w, "  // CompressionThreshold is the minimum size in bytes of request bodies compressed with gzip, zero disables compression.\n")
+	out(w, "  CompressionThreshold int\n\n")
+	out(w, "  // TraceParent is an optional W3C traceparent header value forwarded with requests.\n")
+	out(w, "  TraceParent string\n\n")
+	out(w, "  // TraceState is an optional W3C tracestate header value forwarded with requests.\n")
+	out(w, "  TraceState string\n")
+	out(w, "}\n\n")
+
+	out(w, "// WithTrace returns a copy of the client forwarding the W3C traceparent and tracestate header values.\n")
+	out(w, "func (c *Client) WithTrace(traceparent, tracestate string) *Client {\n")
+	out(w, "  clone := *c\n")
+	out(w, "  clone.TraceParent = traceparent\n")
+	out(w, "  clone.TraceState = tracestate\n")
+	out(w, "  return &clone\n")
+	out(w, "}\n\n")
+
+	for _, m := range s.Methods {
+		// stream
+		if m.Stream {
+			writeStream(w, m)
+			continue
+		}
+
+		writeMethod(w, "Client", m)
+	}
+
+	out(w, "// Batch is a batch of calls sent in a single request with Send, see Client.Batch.\n")
+	out(w, "type Batch struct {\n")
+	out(w, "  client *Client\n")
+	out(w, "  calls  []*BatchCall\n")
+	out(w, "}\n\n")
+
+	out(w, "// Batch returns a new batch of calls, which requires the server to enable batching.\n")
+	out(w, "func (c *Client) Batch() *Batch {\n")
+	out(w, "  return &Batch{client: c}\n")
 	out(w, "}\n\n")
 
 	for _, m := range s.Methods {
+		// streams cannot be batched
+		if m.Stream {
+			continue
+		}
+
 		name := format.GoName(m.Name)
-		out(w, "// %s %s\n", name, m.Description)
-		out(w, "func (c *Client) %s(", name)
+		out(w, "// %s adds a call of %s to the batch.\n", name, m.Name)
+		out(w, "func (b *Batch) %s(", name)
 
-		// input arg
+		// args
+		var args []string
 		if len(m.Inputs) > 0 {
-			out(w, "in %sInput", name)
+			args = append(args, fmt.Sprintf("in %sInput", name))
 		}
-		out(w, ") ")
-
-		// output arg
 		if len(m.Outputs) > 0 {
-			out(w, "(*%sOutput, error) {\n", name)
-			out(w, "  var out %sOutput\n", name)
-		} else {
-			out(w, "error {\n")
+			args = append(args, fmt.Sprintf("out *%sOutput", name))
 		}
+		out(w, "%s) *BatchCall {\n", strings.Join(args, ", "))
 
 		// return
-		out(w, "  return ")
-		if len(m.Outputs) > 0 {
-			out(w, "&out, ")
-		}
-		out(w, "call(c.HTTPClient, c.AuthToken, c.URL, \"%s\", ", m.Name)
+		out(w, "  return b.add(\"%s\", ", m.Name)
 		if len(m.Inputs) > 0 {
 			out(w, "in, ")
 		} else {
 			out(w, "nil, ")
 		}
 		if len(m.Outputs) > 0 {
-			out(w, "&out)\n")
+			out(w, "out)\n")
 		} else {
 			out(w, "nil)\n")
 		}
-
-		// close
 		out(w, "}\n\n")
 	}
 
 	out(w, "\n%s\n", call)
+	out(w, "\n%s\n", batch)
+
+	if hasStreams(s) {
+		out(w, "\n%s\n", stream)
+	}
+
+	// error predicates
+	for _, e := range catalog.Errors {
+		out(w, "\n// Is%s returns true if err is %s.\n", e.Name, e.Description)
+		out(w, "func Is%s(err error) bool {\n", e.Name)
+		out(w, "  return isType(err, %q)\n", e.Type)
+		out(w, "}\n")
+	}
+
+	out(w, "\n// newRequestID returns a new random request ID.\n")
+	out(w, "func newRequestID() string {\n")
+	out(w, "  var b [16]byte\n")
+	out(w, "  rand.Read(b[:])\n")
+	out(w, "  return hex.EncodeToString(b[:])\n")
+	out(w, "}\n")
 
+	out(w, "\n// isType returns true if err is an Error of the given type.\n")
+	out(w, "func isType(err error, kind string) bool {\n")
+	out(w, "  var e Error\n")
+	out(w, "  return errors.As(err, &e) && e.Type == kind\n")
+	out(w, "}\n")
+
+	return nil
+}
+
+// GenerateWebSocket writes the Go client WebSocket connection implementation to
+// w, which requires the github.com/gorilla/websocket package.
+func GenerateWebSocket(w io.Writer, s *schema.Schema) error {
+	out := fmt.Fprintf
+
+	for _, m := range s.Methods {
+		// streams are not supported
+		if m.Stream {
+			continue
+		}
+
+		writeMethod(w, "Conn", m)
+	}
+
+	out(w, "\n%s\n", conn)
 	return nil
 }
+
+// writeMethod writes the method m of type recv, invoking its call method, to w.
+func writeMethod(w io.Writer, recv string, m schema.Method) {
+	out := fmt.Fprintf
+	name := format.GoName(m.Name)
+
+	out(w, "// %s %s\n", name, m.Description)
+	out(w, "func (c *%s) %s(", recv, name)
+
+	// input arg
+	if len(m.Inputs) > 0 {
+		out(w, "in %sInput", name)
+	}
+	out(w, ") ")
+
+	// output arg
+	if len(m.Outputs) > 0 {
+		out(w, "(*%sOutput, error) {\n", name)
+		out(w, "  var out %sOutput\n", name)
+	} else {
+		out(w, "error {\n")
+	}
+
+	// return
+	out(w, "  return ")
+	if len(m.Outputs) > 0 {
+		out(w, "&out, ")
+	}
+	out(w, "c.call(\"%s\", ", m.Name)
+	if len(m.Inputs) > 0 {
+		out(w, "in, ")
+	} else {
+		out(w, "nil, ")
+	}
+	if len(m.Outputs) > 0 {
+		out(w, "&out)\n")
+	} else {
+		out(w, "nil)\n")
+	}
+
+	// close
+	out(w, "}\n\n")
+}
+
+// writeStream writes the client method and iterator of streaming method m to w.
+func writeStream(w io.Writer, m schema.Method) {
+	out := fmt.Fprintf
+	name := format.GoName(m.Name)
+
+	out(w, "// %s %s\n", name, m.Description)
+	if len(m.Inputs) > 0 {
+		out(w, "func (c *Client) %s(in %sInput) (*%sStream, error) {\n", name, name, name)
+		out(w, "  res, err := c.do(\"%s\", in, \"application/x-ndjson\")\n", m.Name)
+	} else {
+		out(w, "func (c *Client) %s() (*%sStream, error) {\n", name, name)
+		out(w, "  res, err := c.do(\"%s\", nil, \"application/x-ndjson\")\n", m.Name)
+	}
+	out(w, "  if err != nil {\n")
+	out(w, "    return nil, err\n")
+	out(w, "  }\n")
+	out(w, "  return &%sStream{stream: newStream(res)}, nil\n", name)
+	out(w, "}\n\n")
+
+	out(w, "// %sStream is an iterator of %s outputs, which must be closed.\n", name, m.Name)
+	out(w, "type %sStream struct {\n", name)
+	out(w, "  *stream\n")
+	out(w, "  out %sOutput\n", name)
+	out(w, "}\n\n")
+
+	out(w, "// Next decodes the next output, returning false at the end of the stream or on error.\n")
+	out(w, "func (s *%sStream) Next() bool {\n", name)
+	out(w, "  s.out = %sOutput{}\n", name)
+	out(w, "  return s.next(&s.out)\n")
+	out(w, "}\n\n")
+
+	out(w, "// Output returns the current output.\n")
+	out(w, "func (s *%sStream) Output() *%sOutput {\n", name, name)
+	out(w, "  return &s.out\n")
+	out(w, "}\n\n")
+}
+
+// hasStreams returns true if the schema has streaming methods.
+func hasStreams(s *schema.Schema) bool {
+	for _, m := range s.Methods {
+		if m.Stream {
+			return true
+		}
+	}
+	return false
+}
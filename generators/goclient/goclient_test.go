@@ -21,3 +21,28 @@ func TestGenerate(t *testing.T) {
 
 	fixture.Assert(t, "todo_client.go", act.Bytes())
 }
+
+func TestGenerate_stream(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Methods[1].Stream = true
+	s.Methods[2].Stream = true
+
+	var act bytes.Buffer
+	err = goclient.Generate(&act, s)
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_client_stream.go", act.Bytes())
+}
+
+func TestGenerateWebSocket(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = goclient.GenerateWebSocket(&act, schema)
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_client_websocket.go", act.Bytes())
+}
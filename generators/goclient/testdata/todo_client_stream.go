@@ -0,0 +1,490 @@
+// Client is the API client.
+type Client struct {
+  // URL is the required API endpoint address.
+  URL string
+
+  // AuthToken is an optional authentication token.
+  AuthToken string
+
+  // HTTPClient is the client used for making requests, defaulting to http.DefaultClient.
+  HTTPClient *http.Client
+
+  // Codec is an optional codec used for request and response bodies, defaulting to JSON.
+  Codec Codec
+
+  // CompressionThreshold is the minimum size in bytes of request bodies compressed with gzip, zero disables compression.
+  CompressionThreshold int
+
+  // TraceParent is an optional W3C traceparent header value forwarded with requests.
+  TraceParent string
+
+  // TraceState is an optional W3C tracestate header value forwarded with requests.
+  TraceState string
+}
+
+// WithTrace returns a copy of the client forwarding the W3C traceparent and tracestate header values.
+func (c *Client) WithTrace(traceparent, tracestate string) *Client {
+  clone := *c
+  clone.TraceParent = traceparent
+  clone.TraceState = tracestate
+  return &clone
+}
+
+// AddItem adds an item to the list.
+func (c *Client) AddItem(in AddItemInput) error {
+  return c.call("add_item", in, nil)
+}
+
+// GetItems returns all items in the list.
+func (c *Client) GetItems() (*GetItemsStream, error) {
+  res, err := c.do("get_items", nil, "application/x-ndjson")
+  if err != nil {
+    return nil, err
+  }
+  return &GetItemsStream{stream: newStream(res)}, nil
+}
+
+// GetItemsStream is an iterator of get_items outputs, which must be closed.
+type GetItemsStream struct {
+  *stream
+  out GetItemsOutput
+}
+
+// Next decodes the next output, returning false at the end of the stream or on error.
+func (s *GetItemsStream) Next() bool {
+  s.out = GetItemsOutput{}
+  return s.next(&s.out)
+}
+
+// Output returns the current output.
+func (s *GetItemsStream) Output() *GetItemsOutput {
+  return &s.out
+}
+
+// RemoveItem removes an item from the to-do list.
+func (c *Client) RemoveItem(in RemoveItemInput) (*RemoveItemStream, error) {
+  res, err := c.do("remove_item", in, "application/x-ndjson")
+  if err != nil {
+    return nil, err
+  }
+  return &RemoveItemStream{stream: newStream(res)}, nil
+}
+
+// RemoveItemStream is an iterator of remove_item outputs, which must be closed.
+type RemoveItemStream struct {
+  *stream
+  out RemoveItemOutput
+}
+
+// Next decodes the next output, returning false at the end of the stream or on error.
+func (s *RemoveItemStream) Next() bool {
+  s.out = RemoveItemOutput{}
+  return s.next(&s.out)
+}
+
+// Output returns the current output.
+func (s *RemoveItemStream) Output() *RemoveItemOutput {
+  return &s.out
+}
+
+// Batch is a batch of calls sent in a single request with Send, see Client.Batch.
+type Batch struct {
+  client *Client
+  calls  []*BatchCall
+}
+
+// Batch returns a new batch of calls, which requires the server to enable batching.
+func (c *Client) Batch() *Batch {
+  return &Batch{client: c}
+}
+
+// AddItem adds a call of add_item to the batch.
+func (b *Batch) AddItem(in AddItemInput) *BatchCall {
+  return b.add("add_item", in, nil)
+}
+
+
+// Error is an error returned by the client.
+type Error struct {
+	Status     string
+	StatusCode int
+	Type       string
+	Message    string
+	Fields     []FieldError
+	Details    map[string]interface{}
+	RetryAfter time.Duration
+	RequestID  string
+}
+
+// FieldError is a field validation error, with the JSON path of the field.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error implementation.
+func (e Error) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("%s: %d", e.Status, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// Codec is the interface used for encoding request bodies and decoding response bodies.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+// call implementation.
+func (c *Client) call(method string, in, out interface{}) error {
+	res, err := c.do(method, in, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// output params
+	if out != nil && c.Codec != nil {
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		return c.Codec.Unmarshal(b, out)
+	}
+
+	if out != nil {
+		err = json.NewDecoder(res.Body).Decode(out)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// do sends a request for method with input in, returning the response, or an Error
+// for error responses. The Accept header defaults to the request content type.
+func (c *Client) do(method string, in interface{}, accept string) (*http.Response, error) {
+	var body io.Reader
+
+	// default client
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// content type
+	contentType := "application/json"
+	if c.Codec != nil {
+		contentType = c.Codec.ContentType()
+	}
+
+	// input params
+	var encoding string
+	if in != nil {
+		var b []byte
+		var err error
+		if c.Codec != nil {
+			b, err = c.Codec.Marshal(in)
+		} else {
+			b, err = json.Marshal(in)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encoding: %w", err)
+		}
+
+		// compression
+		if c.CompressionThreshold > 0 && len(b) >= c.CompressionThreshold {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write(b)
+			if err := gz.Close(); err != nil {
+				return nil, fmt.Errorf("compressing: %w", err)
+			}
+			b = buf.Bytes()
+			encoding = "gzip"
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	// POST request
+	req, err := http.NewRequest("POST", c.URL+"/"+method, body)
+	if err != nil {
+		return nil, err
+	}
+	if accept == "" {
+		accept = contentType
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", accept)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+
+	// request id
+	requestID := newRequestID()
+	req.Header.Set("X-Request-ID", requestID)
+
+	// trace context
+	if c.TraceParent != "" {
+		req.Header.Set("traceparent", c.TraceParent)
+		if c.TraceState != "" {
+			req.Header.Set("tracestate", c.TraceState)
+		}
+	}
+
+	// auth token
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+
+	// response
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// error
+	if res.StatusCode >= 300 {
+		defer res.Body.Close()
+		var e Error
+		if res.Header.Get("Content-Type") == "application/json" {
+			if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+				return nil, err
+			}
+		}
+		e.Status = http.StatusText(res.StatusCode)
+		e.StatusCode = res.StatusCode
+		e.RequestID = requestID
+		if id := res.Header.Get("X-Request-ID"); id != "" {
+			e.RequestID = id
+		}
+		if n, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			e.RetryAfter = time.Duration(n) * time.Second
+		}
+		return nil, e
+	}
+
+	return res, nil
+}
+
+// errorResponse is an error response body.
+type errorResponse struct {
+	Type      string                 `json:"type"`
+	Message   string                 `json:"message"`
+	Fields    []FieldError           `json:"fields"`
+	Details   map[string]interface{} `json:"details"`
+	RequestID string                 `json:"request_id"`
+}
+
+// toError returns an Error with status code.
+func (e errorResponse) toError(status int) Error {
+	return Error{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Type:       e.Type,
+		Message:    e.Message,
+		Fields:     e.Fields,
+		Details:    e.Details,
+		RequestID:  e.RequestID,
+	}
+}
+
+// BatchCall is a call in a batch, with its output and error set by Batch.Send.
+type BatchCall struct {
+	// Err is the error of the call, if any.
+	Err error
+
+	method string
+	in     interface{}
+	out    interface{}
+}
+
+// add adds a call to the batch.
+func (b *Batch) add(method string, in, out interface{}) *BatchCall {
+	call := &BatchCall{
+		method: method,
+		in:     in,
+		out:    out,
+	}
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// Send sends the calls of the batch in a single request, setting their outputs
+// and errors. The error returned is the error of the batch request itself.
+func (b *Batch) Send() error {
+	type batchCall struct {
+		Method string      `json:"method"`
+		Input  interface{} `json:"input,omitempty"`
+	}
+
+	type batchResult struct {
+		Status int             `json:"status"`
+		Result json.RawMessage `json:"result"`
+		Error  *errorResponse  `json:"error"`
+	}
+
+	if len(b.calls) == 0 {
+		return nil
+	}
+
+	calls := make([]batchCall, len(b.calls))
+	for i, c := range b.calls {
+		calls[i] = batchCall{
+			Method: c.method,
+			Input:  c.in,
+		}
+	}
+
+	// batches are always JSON
+	client := *b.client
+	client.Codec = nil
+
+	var results []batchResult
+	err := client.call("_batch", calls, &results)
+	if err != nil {
+		return err
+	}
+
+	if len(results) != len(b.calls) {
+		return fmt.Errorf("batch: expected %d results, got %d", len(b.calls), len(results))
+	}
+
+	for i, r := range results {
+		c := b.calls[i]
+
+		if r.Error != nil {
+			c.Err = r.Error.toError(r.Status)
+			continue
+		}
+
+		if c.out != nil && len(r.Result) > 0 {
+			c.Err = json.Unmarshal(r.Result, c.out)
+		}
+	}
+
+	return nil
+}
+
+// stream is a newline-delimited JSON response stream.
+type stream struct {
+	res *http.Response
+	dec *json.Decoder
+	err error
+}
+
+// newStream returns a new stream of response res.
+func newStream(res *http.Response) *stream {
+	return &stream{
+		res: res,
+		dec: json.NewDecoder(res.Body),
+	}
+}
+
+// next decodes the next output into out, returning false at the end of the stream or on error.
+func (s *stream) next(out interface{}) bool {
+	if s.err != nil {
+		return false
+	}
+
+	var msg struct {
+		Status int             `json:"status"`
+		Result json.RawMessage `json:"result"`
+		Error  *errorResponse  `json:"error"`
+	}
+
+	err := s.dec.Decode(&msg)
+	if err == io.EOF {
+		return false
+	}
+
+	if err != nil {
+		s.err = err
+		return false
+	}
+
+	if msg.Error != nil {
+		s.err = msg.Error.toError(msg.Status)
+		return false
+	}
+
+	s.err = json.Unmarshal(msg.Result, out)
+	return s.err == nil
+}
+
+// Err returns the error which ended the stream, if any.
+func (s *stream) Err() error {
+	return s.err
+}
+
+// Close closes the stream.
+func (s *stream) Close() error {
+	return s.res.Body.Close()
+}
+
+// IsBadRequest returns true if err is a bad request error.
+func IsBadRequest(err error) bool {
+  return isType(err, "bad_request")
+}
+
+// IsInvalid returns true if err is a validation error.
+func IsInvalid(err error) bool {
+  return isType(err, "invalid")
+}
+
+// IsUnauthorized returns true if err is an unauthorized error.
+func IsUnauthorized(err error) bool {
+  return isType(err, "unauthorized")
+}
+
+// IsForbidden returns true if err is a forbidden error.
+func IsForbidden(err error) bool {
+  return isType(err, "forbidden")
+}
+
+// IsNotFound returns true if err is a not found error.
+func IsNotFound(err error) bool {
+  return isType(err, "not_found")
+}
+
+// IsConflict returns true if err is a conflict error.
+func IsConflict(err error) bool {
+  return isType(err, "conflict")
+}
+
+// IsPreconditionFailed returns true if err is a precondition failed error.
+func IsPreconditionFailed(err error) bool {
+  return isType(err, "precondition_failed")
+}
+
+// IsRateLimited returns true if err is a rate limited error.
+func IsRateLimited(err error) bool {
+  return isType(err, "rate_limited")
+}
+
+// IsInternal returns true if err is an internal server error.
+func IsInternal(err error) bool {
+  return isType(err, "internal")
+}
+
+// IsUnavailable returns true if err is a service unavailable error.
+func IsUnavailable(err error) bool {
+  return isType(err, "unavailable")
+}
+
+// newRequestID returns a new random request ID.
+func newRequestID() string {
+  var b [16]byte
+  rand.Read(b[:])
+  return hex.EncodeToString(b[:])
+}
+
+// isType returns true if err is an Error of the given type.
+func isType(err error, kind string) bool {
+  var e Error
+  return errors.As(err, &e) && e.Type == kind
+}
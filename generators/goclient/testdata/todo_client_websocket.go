@@ -0,0 +1,195 @@
+// AddItem adds an item to the list.
+func (c *Conn) AddItem(in AddItemInput) error {
+  return c.call("add_item", in, nil)
+}
+
+// GetItems returns all items in the list.
+func (c *Conn) GetItems() (*GetItemsOutput, error) {
+  var out GetItemsOutput
+  return &out, c.call("get_items", nil, &out)
+}
+
+// RemoveItem removes an item from the to-do list.
+func (c *Conn) RemoveItem(in RemoveItemInput) (*RemoveItemOutput, error) {
+  var out RemoveItemOutput
+  return &out, c.call("remove_item", in, &out)
+}
+
+
+// ErrClosed is returned by calls of a closed connection.
+var ErrClosed = errors.New("connection closed")
+
+// Notification is a notification sent by the server over a connection.
+type Notification struct {
+	Event string
+	Data  json.RawMessage
+}
+
+// Conn is a WebSocket connection multiplexing calls, see Client.Connect.
+type Conn struct {
+	conn    *websocket.Conn
+	notify  func(Notification)
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	id      uint64
+	pending map[uint64]chan connMessage
+	err     error
+	done    chan struct{}
+}
+
+// connCall is a call sent over a connection.
+type connCall struct {
+	ID     uint64      `json:"id"`
+	Method string      `json:"method"`
+	Input  interface{} `json:"input,omitempty"`
+}
+
+// connMessage is a result or notification received over a connection.
+type connMessage struct {
+	ID     uint64          `json:"id"`
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result"`
+	Error  *errorResponse  `json:"error"`
+	Event  string          `json:"event"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Connect opens a WebSocket connection to the API, which requires the server to
+// enable WebSockets. Notifications are passed to notify when non-nil, which is
+// called from the connection's read loop and must not block.
+func (c *Client) Connect(ctx context.Context, notify func(Notification)) (*Conn, error) {
+	header := http.Header{}
+
+	// trace context
+	if c.TraceParent != "" {
+		header.Set("traceparent", c.TraceParent)
+		if c.TraceState != "" {
+			header.Set("tracestate", c.TraceState)
+		}
+	}
+
+	// auth token
+	if c.AuthToken != "" {
+		header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+
+	url := "ws" + strings.TrimPrefix(c.URL, "http") + "/_websocket"
+	ws, res, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		if res != nil && res.StatusCode >= 300 {
+			return nil, Error{
+				Status:     http.StatusText(res.StatusCode),
+				StatusCode: res.StatusCode,
+			}
+		}
+		return nil, err
+	}
+
+	conn := &Conn{
+		conn:    ws,
+		notify:  notify,
+		pending: make(map[uint64]chan connMessage),
+		done:    make(chan struct{}),
+	}
+
+	go conn.read()
+	return conn, nil
+}
+
+// read reads messages until the connection is closed.
+func (c *Conn) read() {
+	for {
+		var msg connMessage
+		err := c.conn.ReadJSON(&msg)
+		if err != nil {
+			c.mu.Lock()
+			if c.err == nil {
+				c.err = err
+			}
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+
+		// notification
+		if msg.Event != "" {
+			if c.notify != nil {
+				c.notify(Notification{Event: msg.Event, Data: msg.Data})
+			}
+			continue
+		}
+
+		// result
+		c.mu.Lock()
+		ch, ok := c.pending[msg.ID]
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+
+		if ok {
+			ch <- msg
+		}
+	}
+}
+
+// call implementation.
+func (c *Conn) call(method string, in, out interface{}) error {
+	ch := make(chan connMessage, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.id++
+	id := c.id
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(connCall{ID: id, Method: method, Input: in})
+	c.writeMu.Unlock()
+
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error.toError(msg.Status)
+		}
+
+		if out != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, out)
+		}
+
+		return nil
+	case <-c.done:
+		return c.Err()
+	}
+}
+
+// Done returns a channel closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error which closed the connection, if any.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	c.mu.Unlock()
+	return c.conn.Close()
+}
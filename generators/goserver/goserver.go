@@ -1,67 +1,167 @@
 package goserver
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"path"
+	"strings"
 
 	"github.com/apex/rpc/internal/format"
 	"github.com/apex/rpc/schema"
 )
 
+// Options are the server generation options.
+type Options struct {
+	// Types is the name of the package providing input types, if any.
+	Types string
+
+	// Tracing enables generation of per-method logging.
+	Tracing bool
+
+	// Logger is the name of the logger adapter used for per-method logging,
+	// one of Loggers, defaulting to "apex".
+	Logger string
+
+	// Strict enables strict request decoding, rejecting unknown fields.
+	Strict bool
+
+	// Batch enables the /_batch endpoint for multiple calls in one request.
+	Batch bool
+
+	// BatchConcurrency is the maximum number of calls of a batch run
+	// concurrently when non-zero, otherwise they are run sequentially.
+	BatchConcurrency int
+
+	// WebSocket enables the /_websocket endpoint for calls and notifications
+	// over a WebSocket connection, served by the transport/websocket package
+	// which must be imported.
+	WebSocket bool
+
+	// Introspection enables the /_schema endpoint serving the schema, with
+	// private methods and types only for authorized requests.
+	Introspection bool
+
+	// Metrics enables the /_metrics endpoint serving the metrics of
+	// rpc.DefaultMetrics in the Prometheus text exposition format.
+	Metrics bool
+
+	// CompressionThreshold overrides the minimum size in bytes of compressed
+	// responses when non-zero, a negative value disables compression.
+	CompressionThreshold int
+}
+
+// Loggers are the import paths of the logger adapter packages by name.
+var Loggers = map[string]string{
+	"apex": "github.com/apex/rpc/logger/apexlog",
+	"slog": "github.com/apex/rpc/logger/slogger",
+}
+
+// LoggerImport returns the import path of the logger adapter package.
+func (o Options) LoggerImport() (string, error) {
+	name := o.Logger
+	if name == "" {
+		name = "apex"
+	}
+
+	p, ok := Loggers[name]
+	if !ok {
+		return "", fmt.Errorf("unknown logger %q", name)
+	}
+
+	return p, nil
+}
+
 // Generate writes the Go server implementations to w.
-func Generate(w io.Writer, s *schema.Schema, tracing bool, types string) error {
+func Generate(w io.Writer, s *schema.Schema, o Options) error {
+	// logger
+	logger, err := o.LoggerImport()
+	if err != nil {
+		return err
+	}
+
 	// router
-	err := writeRouter(w, s, types)
+	err = writeRouter(w, s, o)
 	if err != nil {
 		return fmt.Errorf("writing router: %w", err)
 	}
 
 	// method stubs
-	err = writeMethods(w, s, tracing, types)
+	var pkg string
+	if o.Tracing {
+		pkg = path.Base(logger)
+	}
+
+	err = writeMethods(w, s, pkg, o.Types)
 	if err != nil {
 		return fmt.Errorf("writing methods: %w", err)
 	}
 
+	// schema
+	if o.Introspection {
+		err = writeSchema(w, s)
+		if err != nil {
+			return fmt.Errorf("writing schema: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // writeRouter writes the routing implementation to w.
-func writeRouter(w io.Writer, s *schema.Schema, types string) error {
+func writeRouter(w io.Writer, s *schema.Schema, o Options) error {
 	out := fmt.Fprintf
 	out(w, "// ServeHTTP implementation.\n")
 	out(w, "func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {\n")
+	out(w, "  r = rpc.WithRequestID(r)\n")
+	out(w, "  r = rpc.WithTraceContext(r)\n")
+	if o.CompressionThreshold != 0 {
+		out(w, "  w = rpc.NewResponseWriter(w, r, rpc.CompressionThreshold(%d))\n", o.CompressionThreshold)
+	} else {
+		out(w, "  w = rpc.NewResponseWriter(w, r)\n")
+	}
+	out(w, "  defer rpc.Recover(w, r)\n\n")
 	out(w, "  if r.Method == \"GET\" {\n")
 	out(w, "    switch r.URL.Path {\n")
-	out(w, "      case \"/_health\":\n")
-	out(w, "        rpc.WriteHealth(w, s)\n")
+	out(w, "      case \"/_health\", \"/_health/ready\":\n")
+	out(w, "        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Readiness, s))\n")
+	out(w, "      case \"/_health/live\":\n")
+	out(w, "        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Liveness, s))\n")
+	if o.Metrics {
+		out(w, "      case \"/_metrics\":\n")
+		out(w, "        rpc.WriteMetrics(w, rpc.DefaultMetrics)\n")
+	}
+	if o.Introspection {
+		out(w, "      case \"/_schema\":\n")
+		out(w, "        rpc.WriteSchema(w, r, s, schemaDocument)\n")
+	}
+	if o.WebSocket {
+		out(w, "      case \"/_websocket\":\n")
+		out(w, "        websocket.Serve(w, r, s, s.call)\n")
+	}
 	out(w, "      default:\n")
-	out(w, "        rpc.WriteError(w, rpc.BadRequest(\"Invalid method\"))\n")
+	out(w, "        rpc.WriteError(w, rpc.ErrInvalidMethod)\n")
 	out(w, "    }\n")
 	out(w, "    return\n")
 	out(w, "  }\n\n")
 	out(w, "  if r.Method == \"POST\" {\n")
-	out(w, "    ctx := rpc.NewRequestContext(r.Context(), r)\n")
-	out(w, "    var res interface{}\n")
-	out(w, "    var err error\n")
-	out(w, "    switch r.URL.Path {\n")
-	for _, m := range s.Methods {
-		out(w, "      case \"/%s\":\n", m.Name)
-		// parse input
-		if len(m.Inputs) > 0 {
-			out(w, "        var in %s\n", format.GoInputType(types, m.Name))
-			out(w, "        err = rpc.ReadRequest(r, &in)\n")
-			out(w, "        if err != nil {\n")
-			out(w, "          break\n")
-			out(w, "        }\n")
-			out(w, "        res, err = s.%s(ctx, in)\n", format.JsName(m.Name))
-		} else {
-			out(w, "        res, err = s.%s(ctx)\n", format.JsName(m.Name))
+	out(w, "    defer rpc.LogAccess(w, r)()\n")
+	if o.Batch {
+		out(w, "    if r.URL.Path == \"/_batch\" {\n")
+		var options string
+		if o.BatchConcurrency > 0 {
+			options += fmt.Sprintf(", rpc.BatchConcurrency(%d)", o.BatchConcurrency)
 		}
+		if read := readOptions(o, batchLimits(s)); read != "" {
+			options += fmt.Sprintf(", rpc.BatchReadOptions(%s)", strings.TrimPrefix(read, ", "))
+		}
+		out(w, "      rpc.ServeBatch(w, r, s.call%s)\n", options)
+		out(w, "      return\n")
+		out(w, "    }\n\n")
 	}
-	out(w, "      default:\n")
-	out(w, "        err = rpc.BadRequest(\"Invalid method\")\n")
-	out(w, "    }\n")
-	out(w, "\n")
+	out(w, "    ctx := rpc.NewRequestContext(r.Context(), r)\n")
+	out(w, "    res, err := s.call(ctx, w, r, r.URL.Path)\n")
 	out(w, "    if err != nil {\n")
 	out(w, "      rpc.WriteError(w, err)\n")
 	out(w, "      return\n")
@@ -70,12 +170,81 @@ func writeRouter(w io.Writer, s *schema.Schema, types string) error {
 	out(w, "    rpc.WriteResponse(w, res)\n")
 	out(w, "    return\n")
 	out(w, "  }\n")
+	out(w, "}\n\n")
+
+	out(w, "// call invokes the method at path with request r, where w is nil for calls of batch requests and WebSocket connections.\n")
+	out(w, "func (s *Server) call(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (res interface{}, err error) {\n")
+	out(w, "  switch path {\n")
+	for _, m := range s.Methods {
+		out(w, "    case \"/%s\":\n", m.Name)
+		out(w, "      defer rpc.DefaultMetrics.Observe(%q)(&err)\n", m.Name)
+		out(w, "      ctx, end := rpc.StartSpan(ctx, %q)\n", m.Name)
+		out(w, "      defer end(&err)\n")
+		// parse input
+		if len(m.Inputs) > 0 {
+			out(w, "      var in %s\n", format.GoInputType(o.Types, m.Name))
+			out(w, "      err = rpc.ReadRequest(r, &in%s)\n", readOptions(o, s.MethodLimits(m)))
+			out(w, "      if err != nil {\n")
+			out(w, "        return nil, err\n")
+			out(w, "      }\n")
+		}
+
+		// stream
+		if m.Stream {
+			out(w, "      var stream *rpc.Stream\n")
+			out(w, "      stream, err = rpc.NewStream(w, r)\n")
+			out(w, "      if err != nil {\n")
+			out(w, "        return nil, err\n")
+			out(w, "      }\n")
+		}
+
+		// invoke
+		var args, in string
+		if len(m.Inputs) > 0 {
+			args = ", in"
+			in = "in"
+		} else {
+			in = "nil"
+		}
+
+		if m.Stream {
+			args += fmt.Sprintf(", %sSender{stream}", format.GoName(m.Name))
+			out(w, "      _, err = rpc.Invoke(ctx, s, %q, %s, func(ctx context.Context, v interface{}) (interface{}, error) {\n", m.Name, in)
+			writeInputAssertion(w, o, m)
+			out(w, "        return s.%s(ctx%s)\n", format.JsName(m.Name), args)
+			out(w, "      })\n")
+			out(w, "      return stream, err\n")
+		} else {
+			out(w, "      return rpc.Invoke(ctx, s, %q, %s, func(ctx context.Context, v interface{}) (interface{}, error) {\n", m.Name, in)
+			writeInputAssertion(w, o, m)
+			out(w, "        return s.%s(ctx%s)\n", format.JsName(m.Name), args)
+			out(w, "      })\n")
+		}
+	}
+	out(w, "    default:\n")
+	out(w, "      return nil, rpc.ErrInvalidMethod\n")
+	out(w, "  }\n")
 	out(w, "}\n")
 	return nil
 }
 
-// writeMethods writes method stubs to w.
-func writeMethods(w io.Writer, s *schema.Schema, tracing bool, types string) error {
+// writeInputAssertion writes the assertion of the input v of method m passed
+// to the Invoke handler, which interceptors may have replaced, to w.
+func writeInputAssertion(w io.Writer, o Options, m schema.Method) {
+	if len(m.Inputs) == 0 {
+		return
+	}
+
+	out := fmt.Fprintf
+	out(w, "        in, ok := v.(%s)\n", format.GoInputType(o.Types, m.Name))
+	out(w, "        if !ok {\n")
+	out(w, "          return nil, rpc.Internal(\"Invalid input type for method %s\")\n", m.Name)
+	out(w, "        }\n")
+}
+
+// writeMethods writes method stubs to w, logging with the
+// logger adapter package pkg unless empty.
+func writeMethods(w io.Writer, s *schema.Schema, pkg, types string) error {
 	out := fmt.Fprintf
 
 	for _, m := range s.Methods {
@@ -83,18 +252,23 @@ func writeMethods(w io.Writer, s *schema.Schema, tracing bool, types string) err
 		out(w, "// %s %s\n", format.JsName(m.Name), m.Description)
 
 		// method signature
+		var params, args string
 		if len(m.Inputs) > 0 {
-			out(w, "func (s *Server) %s(ctx context.Context, in %s) (interface{}, error) {\n", format.JsName(m.Name), format.GoInputType(types, m.Name))
-		} else {
-			out(w, "func (s *Server) %s(ctx context.Context) (interface{}, error) {\n", format.JsName(m.Name))
+			params += fmt.Sprintf(", in %s", format.GoInputType(types, m.Name))
+			args += ", in"
 		}
+		if m.Stream {
+			params += fmt.Sprintf(", send %sSender", format.GoName(m.Name))
+			args += ", send"
+		}
+		out(w, "func (s *Server) %s(ctx context.Context%s) (interface{}, error) {\n", format.JsName(m.Name), params)
 
 		// tracing
-		if tracing {
-			out(w, "  logs := log.FromContext(ctx).WithField(\"method\", %q)\n\n", m.Name)
+		if pkg != "" {
+			out(w, "  logs := %s.FromContext(ctx).WithFields(rpc.Fields{\"method\": %q})\n\n", pkg, m.Name)
 
 			if len(m.Inputs) > 0 {
-				out(w, "  logs = logs.WithFields(log.Fields{\n")
+				out(w, "  logs = logs.WithFields(rpc.Fields{\n")
 				for _, f := range m.Inputs {
 					out(w, "    %q: in.%s,\n", f.Name, format.GoName(f.Name))
 				}
@@ -104,35 +278,135 @@ func writeMethods(w io.Writer, s *schema.Schema, tracing bool, types string) err
 		}
 
 		// invoke method
-		if len(m.Outputs) > 0 {
+		if len(m.Outputs) > 0 && !m.Stream {
 			out(w, "  res, err := s.%s", format.GoName(m.Name))
 		} else {
 			out(w, "  err := s.%s", format.GoName(m.Name))
 		}
 
-		if tracing {
-			if len(m.Inputs) > 0 {
-				out(w, "(log.NewContext(ctx, logs), in)\n")
-			} else {
-				out(w, "(log.NewContext(ctx, logs))\n")
-			}
+		if pkg != "" {
+			out(w, "(logs.NewContext(ctx)%s)\n", args)
 		} else {
-			if len(m.Inputs) > 0 {
-				out(w, "(ctx, in)\n")
-			} else {
-				out(w, "(ctx)\n")
-			}
+			out(w, "(ctx%s)\n", args)
 		}
 
-		if len(m.Outputs) > 0 {
+		if len(m.Outputs) > 0 && !m.Stream {
 			out(w, "  return res, err\n")
 		} else {
 			out(w, "  return nil, err\n")
 		}
 
 		out(w, "}\n")
+
+		// stream sender
+		if m.Stream {
+			name := format.GoName(m.Name)
+			out(w, "\n")
+			out(w, "// %sSender sends the outputs of %s.\n", name, m.Name)
+			out(w, "type %sSender struct {\n", name)
+			out(w, "  stream *rpc.Stream\n")
+			out(w, "}\n\n")
+			out(w, "// Send sends an output, returning an error when the client disconnected.\n")
+			out(w, "func (s %sSender) Send(out *%s) error {\n", name, format.GoOutputType(types, m.Name))
+			out(w, "  return s.stream.Send(out)\n")
+			out(w, "}\n")
+		}
 	}
 	out(w, "\n")
 
 	return nil
 }
+
+// writeSchema writes the schema document served by the /_schema endpoint to w.
+func writeSchema(w io.Writer, s *schema.Schema) error {
+	out := fmt.Fprintf
+
+	p, err := s.Public()
+	if err != nil {
+		return err
+	}
+
+	public, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	private, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	out(w, "// schemaDocument is the schema served by the /_schema endpoint.\n")
+	out(w, "var schemaDocument = rpc.SchemaDocument{\n")
+	out(w, "  Version: %q,\n", s.Version)
+	out(w, "  Public:  []byte(%q),\n", public)
+	if !bytes.Equal(public, private) {
+		out(w, "  Private: []byte(%q),\n", private)
+	}
+	out(w, "}\n")
+
+	return nil
+}
+
+// batchLimits returns the limits of batch requests, the loosest of the method
+// limits so that batches of valid calls are accepted, as each call is read with
+// its own limits. The depth allows for the array of calls and their objects,
+// and the array length is left to the batch size.
+func batchLimits(s *schema.Schema) (l schema.Limits) {
+	for i, m := range s.Methods {
+		ml := s.MethodLimits(m)
+		if i == 0 {
+			l = ml
+			continue
+		}
+
+		l.BodyBytes = loosest(l.BodyBytes, ml.BodyBytes)
+		l.Depth = int(loosest(int64(l.Depth), int64(ml.Depth)))
+		l.StringLength = int(loosest(int64(l.StringLength), int64(ml.StringLength)))
+	}
+
+	if l.Depth > 0 {
+		l.Depth += 2
+	}
+
+	l.ArrayLength = 0
+	return
+}
+
+// loosest returns the loosest of limits a and b, where zero is unlimited.
+func loosest(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// readOptions returns the rpc.ReadRequest options.
+func readOptions(o Options, l schema.Limits) (s string) {
+	if o.Strict {
+		s += ", rpc.Strict()"
+	}
+
+	if l.BodyBytes > 0 {
+		s += fmt.Sprintf(", rpc.MaxBodyBytes(%d)", l.BodyBytes)
+	}
+
+	if l.Depth > 0 {
+		s += fmt.Sprintf(", rpc.MaxDepth(%d)", l.Depth)
+	}
+
+	if l.ArrayLength > 0 {
+		s += fmt.Sprintf(", rpc.MaxArrayLength(%d)", l.ArrayLength)
+	}
+
+	if l.StringLength > 0 {
+		s += fmt.Sprintf(", rpc.MaxStringLength(%d)", l.StringLength)
+	}
+
+	return
+}
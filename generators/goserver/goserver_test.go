@@ -16,7 +16,7 @@ func TestGenerate_noTypes(t *testing.T) {
 	assert.NoError(t, err, "loading schema")
 
 	var act bytes.Buffer
-	err = goserver.Generate(&act, schema, false, "")
+	err = goserver.Generate(&act, schema, goserver.Options{})
 	assert.NoError(t, err, "generating")
 
 	fixture.Assert(t, "todo_server_no_types.go", act.Bytes())
@@ -26,8 +26,134 @@ func TestGenerate_types(t *testing.T) {
 	assert.NoError(t, err, "loading schema")
 
 	var act bytes.Buffer
-	err = goserver.Generate(&act, schema, false, "api")
+	err = goserver.Generate(&act, schema, goserver.Options{Types: "api"})
 	assert.NoError(t, err, "generating")
 
 	fixture.Assert(t, "todo_server_types.go", act.Bytes())
 }
+
+func TestGenerate_strict(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, schema, goserver.Options{Types: "api", Strict: true})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_strict.go", act.Bytes())
+}
+
+func TestGenerate_limits(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Limits = &schema.Limits{BodyBytes: 1 << 20, Depth: 10}
+	s.Methods[0].Limits = &schema.Limits{BodyBytes: 1024, StringLength: 100}
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, s, goserver.Options{Types: "api", CompressionThreshold: 4096})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_limits.go", act.Bytes())
+}
+
+func TestGenerate_logger(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, schema, goserver.Options{Types: "api", Tracing: true, Logger: "slog"})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_logger.go", act.Bytes())
+
+	err = goserver.Generate(&act, schema, goserver.Options{Tracing: true, Logger: "zap"})
+	assert.EqualError(t, err, `unknown logger "zap"`)
+}
+
+func TestGenerate_batch(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, schema, goserver.Options{Types: "api", Strict: true, Batch: true, BatchConcurrency: 4})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_batch.go", act.Bytes())
+}
+
+func TestGenerate_batchLimits(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Limits = &schema.Limits{BodyBytes: 1 << 20, Depth: 10, ArrayLength: 100}
+	s.Methods[0].Limits = &schema.Limits{BodyBytes: 1024, StringLength: 100}
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, s, goserver.Options{Types: "api", Batch: true})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_batch_limits.go", act.Bytes())
+}
+
+func TestGenerate_websocket(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, schema, goserver.Options{Types: "api", WebSocket: true})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_websocket.go", act.Bytes())
+}
+
+func TestGenerate_introspection(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Methods[2].Private = true
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, s, goserver.Options{Types: "api", Introspection: true})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_introspection.go", act.Bytes())
+}
+
+func TestGenerate_introspectionPrivateRef(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	item := s.Types["item"]
+	item.Private = true
+	s.Types["item"] = item
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, s, goserver.Options{Types: "api", Introspection: true})
+	assert.EqualError(t, err, `writing schema: method "get_items" field "items" refers to private type "item"`)
+}
+
+func TestGenerate_metrics(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, s, goserver.Options{Types: "api", Metrics: true})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_metrics.go", act.Bytes())
+}
+
+func TestGenerate_stream(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Methods[1].Stream = true
+	s.Methods[2].Stream = true
+
+	var act bytes.Buffer
+	err = goserver.Generate(&act, s, goserver.Options{Types: "api", Tracing: true})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_server_stream.go", act.Bytes())
+}
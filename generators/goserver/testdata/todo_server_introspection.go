@@ -0,0 +1,106 @@
+// ServeHTTP implementation.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  r = rpc.WithRequestID(r)
+  r = rpc.WithTraceContext(r)
+  w = rpc.NewResponseWriter(w, r)
+  defer rpc.Recover(w, r)
+
+  if r.Method == "GET" {
+    switch r.URL.Path {
+      case "/_health", "/_health/ready":
+        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Readiness, s))
+      case "/_health/live":
+        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Liveness, s))
+      case "/_schema":
+        rpc.WriteSchema(w, r, s, schemaDocument)
+      default:
+        rpc.WriteError(w, rpc.ErrInvalidMethod)
+    }
+    return
+  }
+
+  if r.Method == "POST" {
+    defer rpc.LogAccess(w, r)()
+    ctx := rpc.NewRequestContext(r.Context(), r)
+    res, err := s.call(ctx, w, r, r.URL.Path)
+    if err != nil {
+      rpc.WriteError(w, err)
+      return
+    }
+
+    rpc.WriteResponse(w, res)
+    return
+  }
+}
+
+// call invokes the method at path with request r, where w is nil for calls of batch requests and WebSocket connections.
+func (s *Server) call(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (res interface{}, err error) {
+  switch path {
+    case "/add_item":
+      defer rpc.DefaultMetrics.Observe("add_item")(&err)
+      ctx, end := rpc.StartSpan(ctx, "add_item")
+      defer end(&err)
+      var in api.AddItemInput
+      err = rpc.ReadRequest(r, &in)
+      if err != nil {
+        return nil, err
+      }
+      return rpc.Invoke(ctx, s, "add_item", in, func(ctx context.Context, v interface{}) (interface{}, error) {
+        in, ok := v.(api.AddItemInput)
+        if !ok {
+          return nil, rpc.Internal("Invalid input type for method add_item")
+        }
+        return s.addItem(ctx, in)
+      })
+    case "/get_items":
+      defer rpc.DefaultMetrics.Observe("get_items")(&err)
+      ctx, end := rpc.StartSpan(ctx, "get_items")
+      defer end(&err)
+      return rpc.Invoke(ctx, s, "get_items", nil, func(ctx context.Context, v interface{}) (interface{}, error) {
+        return s.getItems(ctx)
+      })
+    case "/remove_item":
+      defer rpc.DefaultMetrics.Observe("remove_item")(&err)
+      ctx, end := rpc.StartSpan(ctx, "remove_item")
+      defer end(&err)
+      var in api.RemoveItemInput
+      err = rpc.ReadRequest(r, &in)
+      if err != nil {
+        return nil, err
+      }
+      return rpc.Invoke(ctx, s, "remove_item", in, func(ctx context.Context, v interface{}) (interface{}, error) {
+        in, ok := v.(api.RemoveItemInput)
+        if !ok {
+          return nil, rpc.Internal("Invalid input type for method remove_item")
+        }
+        return s.removeItem(ctx, in)
+      })
+    default:
+      return nil, rpc.ErrInvalidMethod
+  }
+}
+
+// addItem adds an item to the list.
+func (s *Server) addItem(ctx context.Context, in api.AddItemInput) (interface{}, error) {
+  err := s.AddItem(ctx, in)
+  return nil, err
+}
+
+// getItems returns all items in the list.
+func (s *Server) getItems(ctx context.Context) (interface{}, error) {
+  res, err := s.GetItems(ctx)
+  return res, err
+}
+
+// removeItem removes an item from the to-do list.
+func (s *Server) removeItem(ctx context.Context, in api.RemoveItemInput) (interface{}, error) {
+  res, err := s.RemoveItem(ctx, in)
+  return res, err
+}
+
+// schemaDocument is the schema served by the /_schema endpoint.
+var schemaDocument = rpc.SchemaDocument{
+  Version: "1.0.0",
+  Public:  []byte("{\"name\":\"todo\",\"version\":\"1.0.0\",\"description\":\"A to-do list example.\",\"methods\":[{\"name\":\"add_item\",\"description\":\"adds an item to the list.\",\"inputs\":[{\"name\":\"item\",\"description\":\"the item to add.\",\"required\":true,\"type\":\"string\"}]},{\"name\":\"get_items\",\"description\":\"returns all items in the list.\",\"outputs\":[{\"name\":\"items\",\"description\":\"the list of to-do items.\",\"type\":\"array\",\"items\":{\"$ref\":\"#/types/item\"}}]}],\"types\":{\"item\":{\"name\":\"item\",\"description\":\"is a to-do item.\",\"properties\":[{\"name\":\"id\",\"description\":\"the id of the item.\",\"readonly\":true,\"type\":\"integer\"},{\"name\":\"text\",\"description\":\"the to-do item text.\",\"required\":true,\"type\":\"string\"},{\"name\":\"created_at\",\"description\":\"the time the to-do item was created.\",\"type\":\"timestamp\"}]}},\"go\":{}}"),
+  Private: []byte("{\"name\":\"todo\",\"version\":\"1.0.0\",\"description\":\"A to-do list example.\",\"methods\":[{\"name\":\"add_item\",\"description\":\"adds an item to the list.\",\"inputs\":[{\"name\":\"item\",\"description\":\"the item to add.\",\"required\":true,\"type\":\"string\"}]},{\"name\":\"get_items\",\"description\":\"returns all items in the list.\",\"outputs\":[{\"name\":\"items\",\"description\":\"the list of to-do items.\",\"type\":\"array\",\"items\":{\"$ref\":\"#/types/item\"}}]},{\"name\":\"remove_item\",\"description\":\"removes an item from the to-do list.\",\"private\":true,\"inputs\":[{\"name\":\"id\",\"description\":\"the id of the item to remove.\",\"type\":\"integer\"}],\"outputs\":[{\"name\":\"item\",\"description\":\"the item removed.\",\"type\":{\"$ref\":\"#/types/item\"}}]}],\"types\":{\"item\":{\"name\":\"item\",\"description\":\"is a to-do item.\",\"properties\":[{\"name\":\"id\",\"description\":\"the id of the item.\",\"readonly\":true,\"type\":\"integer\"},{\"name\":\"text\",\"description\":\"the to-do item text.\",\"required\":true,\"type\":\"string\"},{\"name\":\"created_at\",\"description\":\"the time the to-do item was created.\",\"type\":\"timestamp\"}]}},\"go\":{}}"),
+}
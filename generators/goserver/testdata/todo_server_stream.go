@@ -0,0 +1,145 @@
+// ServeHTTP implementation.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  r = rpc.WithRequestID(r)
+  r = rpc.WithTraceContext(r)
+  w = rpc.NewResponseWriter(w, r)
+  defer rpc.Recover(w, r)
+
+  if r.Method == "GET" {
+    switch r.URL.Path {
+      case "/_health", "/_health/ready":
+        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Readiness, s))
+      case "/_health/live":
+        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Liveness, s))
+      default:
+        rpc.WriteError(w, rpc.ErrInvalidMethod)
+    }
+    return
+  }
+
+  if r.Method == "POST" {
+    defer rpc.LogAccess(w, r)()
+    ctx := rpc.NewRequestContext(r.Context(), r)
+    res, err := s.call(ctx, w, r, r.URL.Path)
+    if err != nil {
+      rpc.WriteError(w, err)
+      return
+    }
+
+    rpc.WriteResponse(w, res)
+    return
+  }
+}
+
+// call invokes the method at path with request r, where w is nil for calls of batch requests and WebSocket connections.
+func (s *Server) call(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (res interface{}, err error) {
+  switch path {
+    case "/add_item":
+      defer rpc.DefaultMetrics.Observe("add_item")(&err)
+      ctx, end := rpc.StartSpan(ctx, "add_item")
+      defer end(&err)
+      var in api.AddItemInput
+      err = rpc.ReadRequest(r, &in)
+      if err != nil {
+        return nil, err
+      }
+      return rpc.Invoke(ctx, s, "add_item", in, func(ctx context.Context, v interface{}) (interface{}, error) {
+        in, ok := v.(api.AddItemInput)
+        if !ok {
+          return nil, rpc.Internal("Invalid input type for method add_item")
+        }
+        return s.addItem(ctx, in)
+      })
+    case "/get_items":
+      defer rpc.DefaultMetrics.Observe("get_items")(&err)
+      ctx, end := rpc.StartSpan(ctx, "get_items")
+      defer end(&err)
+      var stream *rpc.Stream
+      stream, err = rpc.NewStream(w, r)
+      if err != nil {
+        return nil, err
+      }
+      _, err = rpc.Invoke(ctx, s, "get_items", nil, func(ctx context.Context, v interface{}) (interface{}, error) {
+        return s.getItems(ctx, GetItemsSender{stream})
+      })
+      return stream, err
+    case "/remove_item":
+      defer rpc.DefaultMetrics.Observe("remove_item")(&err)
+      ctx, end := rpc.StartSpan(ctx, "remove_item")
+      defer end(&err)
+      var in api.RemoveItemInput
+      err = rpc.ReadRequest(r, &in)
+      if err != nil {
+        return nil, err
+      }
+      var stream *rpc.Stream
+      stream, err = rpc.NewStream(w, r)
+      if err != nil {
+        return nil, err
+      }
+      _, err = rpc.Invoke(ctx, s, "remove_item", in, func(ctx context.Context, v interface{}) (interface{}, error) {
+        in, ok := v.(api.RemoveItemInput)
+        if !ok {
+          return nil, rpc.Internal("Invalid input type for method remove_item")
+        }
+        return s.removeItem(ctx, in, RemoveItemSender{stream})
+      })
+      return stream, err
+    default:
+      return nil, rpc.ErrInvalidMethod
+  }
+}
+
+// addItem adds an item to the list.
+func (s *Server) addItem(ctx context.Context, in api.AddItemInput) (interface{}, error) {
+  logs := apexlog.FromContext(ctx).WithFields(rpc.Fields{"method": "add_item"})
+
+  logs = logs.WithFields(rpc.Fields{
+    "item": in.Item,
+  })
+
+  err := s.AddItem(logs.NewContext(ctx), in)
+  return nil, err
+}
+
+// getItems returns all items in the list.
+func (s *Server) getItems(ctx context.Context, send GetItemsSender) (interface{}, error) {
+  logs := apexlog.FromContext(ctx).WithFields(rpc.Fields{"method": "get_items"})
+
+
+  err := s.GetItems(logs.NewContext(ctx), send)
+  return nil, err
+}
+
+// GetItemsSender sends the outputs of get_items.
+type GetItemsSender struct {
+  stream *rpc.Stream
+}
+
+// Send sends an output, returning an error when the client disconnected.
+func (s GetItemsSender) Send(out *api.GetItemsOutput) error {
+  return s.stream.Send(out)
+}
+
+// removeItem removes an item from the to-do list.
+func (s *Server) removeItem(ctx context.Context, in api.RemoveItemInput, send RemoveItemSender) (interface{}, error) {
+  logs := apexlog.FromContext(ctx).WithFields(rpc.Fields{"method": "remove_item"})
+
+  logs = logs.WithFields(rpc.Fields{
+    "id": in.ID,
+  })
+
+  err := s.RemoveItem(logs.NewContext(ctx), in, send)
+  return nil, err
+}
+
+// RemoveItemSender sends the outputs of remove_item.
+type RemoveItemSender struct {
+  stream *rpc.Stream
+}
+
+// Send sends an output, returning an error when the client disconnected.
+func (s RemoveItemSender) Send(out *api.RemoveItemOutput) error {
+  return s.stream.Send(out)
+}
+
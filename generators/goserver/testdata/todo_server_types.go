@@ -1,40 +1,26 @@
 // ServeHTTP implementation.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  r = rpc.WithRequestID(r)
+  r = rpc.WithTraceContext(r)
+  w = rpc.NewResponseWriter(w, r)
+  defer rpc.Recover(w, r)
+
   if r.Method == "GET" {
     switch r.URL.Path {
-      case "/_health":
-        rpc.WriteHealth(w, s)
+      case "/_health", "/_health/ready":
+        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Readiness, s))
+      case "/_health/live":
+        rpc.WriteHealthReport(w, rpc.DefaultHealth.Check(r.Context(), rpc.Liveness, s))
       default:
-        rpc.WriteError(w, rpc.BadRequest("Invalid method"))
+        rpc.WriteError(w, rpc.ErrInvalidMethod)
     }
     return
   }
 
   if r.Method == "POST" {
+    defer rpc.LogAccess(w, r)()
     ctx := rpc.NewRequestContext(r.Context(), r)
-    var res interface{}
-    var err error
-    switch r.URL.Path {
-      case "/add_item":
-        var in api.AddItemInput
-        err = rpc.ReadRequest(r, &in)
-        if err != nil {
-          break
-        }
-        res, err = s.addItem(ctx, in)
-      case "/get_items":
-        res, err = s.getItems(ctx)
-      case "/remove_item":
-        var in api.RemoveItemInput
-        err = rpc.ReadRequest(r, &in)
-        if err != nil {
-          break
-        }
-        res, err = s.removeItem(ctx, in)
-      default:
-        err = rpc.BadRequest("Invalid method")
-    }
-
+    res, err := s.call(ctx, w, r, r.URL.Path)
     if err != nil {
       rpc.WriteError(w, err)
       return
@@ -45,6 +31,53 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
   }
 }
 
+// call invokes the method at path with request r, where w is nil for calls of batch requests and WebSocket connections.
+func (s *Server) call(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (res interface{}, err error) {
+  switch path {
+    case "/add_item":
+      defer rpc.DefaultMetrics.Observe("add_item")(&err)
+      ctx, end := rpc.StartSpan(ctx, "add_item")
+      defer end(&err)
+      var in api.AddItemInput
+      err = rpc.ReadRequest(r, &in)
+      if err != nil {
+        return nil, err
+      }
+      return rpc.Invoke(ctx, s, "add_item", in, func(ctx context.Context, v interface{}) (interface{}, error) {
+        in, ok := v.(api.AddItemInput)
+        if !ok {
+          return nil, rpc.Internal("Invalid input type for method add_item")
+        }
+        return s.addItem(ctx, in)
+      })
+    case "/get_items":
+      defer rpc.DefaultMetrics.Observe("get_items")(&err)
+      ctx, end := rpc.StartSpan(ctx, "get_items")
+      defer end(&err)
+      return rpc.Invoke(ctx, s, "get_items", nil, func(ctx context.Context, v interface{}) (interface{}, error) {
+        return s.getItems(ctx)
+      })
+    case "/remove_item":
+      defer rpc.DefaultMetrics.Observe("remove_item")(&err)
+      ctx, end := rpc.StartSpan(ctx, "remove_item")
+      defer end(&err)
+      var in api.RemoveItemInput
+      err = rpc.ReadRequest(r, &in)
+      if err != nil {
+        return nil, err
+      }
+      return rpc.Invoke(ctx, s, "remove_item", in, func(ctx context.Context, v interface{}) (interface{}, error) {
+        in, ok := v.(api.RemoveItemInput)
+        if !ok {
+          return nil, rpc.Internal("Invalid input type for method remove_item")
+        }
+        return s.removeItem(ctx, in)
+      })
+    default:
+      return nil, rpc.ErrInvalidMethod
+  }
+}
+
 // addItem adds an item to the list.
 func (s *Server) addItem(ctx context.Context, in api.AddItemInput) (interface{}, error) {
   err := s.AddItem(ctx, in)
@@ -158,11 +158,12 @@ func writeValidation(w io.Writer, name string, fields []schema.Field) error {
 	recv := strings.ToLower(name)[0]
 	out(w, "// Validate implementation.\n")
 	out(w, "func (%c *%s) Validate() error {\n", recv, name)
+	out(w, "  var errs rpc.ValidationErrors\n\n")
 	for _, f := range fields {
 		writeFieldDefaults(w, f, recv)
 		writeFieldValidation(w, f, recv)
 	}
-	out(w, "  return nil\n")
+	out(w, "  return errs.Err()\n")
 	out(w, "}\n")
 	return nil
 }
@@ -197,7 +198,7 @@ func writeFieldValidation(w io.Writer, f schema.Field, recv byte) error {
 	name := format.GoName(f.Name)
 
 	writeError := func(msg string) {
-		out(w, "    return rpc.ValidationError{ Field: %q, Message: %q }\n", f.Name, msg)
+		out(w, "    errs.Add(%q, %q)\n", f.Name, msg)
 	}
 
 	// required
@@ -230,13 +231,23 @@ func writeFieldValidation(w io.Writer, f schema.Field, recv byte) error {
 		out(w, "  }\n\n")
 	}
 
+	// validate referenced types, where optional values are
+	// validated when present, that is when not their zero value
+	if f.Type.Ref.Value != "" {
+		if f.Required {
+			out(w, "  errs.Merge(%q, %c.%s.Validate())\n\n", f.Name, recv, name)
+		} else {
+			out(w, "  if !rpc.IsZero(%c.%s) {\n", recv, name)
+			out(w, "    errs.Merge(%q, %c.%s.Validate())\n", f.Name, recv, name)
+			out(w, "  }\n\n")
+		}
+	}
+
 	// validate the children of non-primitive arrays
 	// TODO: HasRef() or similar?
 	if f.Type.Type == schema.Array && f.Items.Ref.Value != "" {
 		out(w, "  for i, v := range %c.%s {\n", recv, name)
-		out(w, "    if err := v.Validate(); err != nil {\n")
-		out(w, "      return fmt.Errorf(\"element %%d: %%s\", i, err.Error())\n")
-		out(w, "    }\n")
+		out(w, "    errs.Merge(fmt.Sprintf(\"%s[%%d]\", i), v.Validate())\n", f.Name)
 		out(w, "  }\n\n")
 	}
 
@@ -32,3 +32,30 @@ func TestGenerate_noValidate(t *testing.T) {
 
 	fixture.Assert(t, "todo_types_no_validate.go", act.Bytes())
 }
+
+func TestGenerate_validateNested(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Methods[0].Inputs = append(s.Methods[0].Inputs, schema.Field{
+		Name:        "parent",
+		Description: "the parent item.",
+		Required:    true,
+		Type:        schema.TypeObject{Ref: schema.Ref{Value: "#/types/item"}},
+	}, schema.Field{
+		Name:        "origin",
+		Description: "the item this item was copied from.",
+		Type:        schema.TypeObject{Ref: schema.Ref{Value: "#/types/item"}},
+	}, schema.Field{
+		Name:        "related",
+		Description: "the related items.",
+		Type:        schema.TypeObject{Type: schema.Array},
+		Items:       schema.ItemsObject{Ref: schema.Ref{Value: "#/types/item"}},
+	})
+
+	var act bytes.Buffer
+	err = gotypes.Generate(&act, s, true)
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_types_validate_nested.go", act.Bytes())
+}
@@ -12,11 +12,13 @@ type Item struct {
 
 // Validate implementation.
 func (i *Item) Validate() error {
+  var errs rpc.ValidationErrors
+
   if i.Text == "" {
-    return rpc.ValidationError{ Field: "text", Message: "is required" }
+    errs.Add("text", "is required")
   }
 
-  return nil
+  return errs.Err()
 }
 
 // AddItemInput params.
@@ -27,11 +29,13 @@ type AddItemInput struct {
 
 // Validate implementation.
 func (a *AddItemInput) Validate() error {
+  var errs rpc.ValidationErrors
+
   if a.Item == "" {
-    return rpc.ValidationError{ Field: "item", Message: "is required" }
+    errs.Add("item", "is required")
   }
 
-  return nil
+  return errs.Err()
 }
 
 // GetItemsOutput params.
@@ -48,7 +52,9 @@ type RemoveItemInput struct {
 
 // Validate implementation.
 func (r *RemoveItemInput) Validate() error {
-  return nil
+  var errs rpc.ValidationErrors
+
+  return errs.Err()
 }
 
 // RemoveItemOutput params.
@@ -0,0 +1,94 @@
+// Item is a to-do item.
+type Item struct {
+  // CreatedAt is the time the to-do item was created.
+  CreatedAt time.Time `json:"created_at"`
+
+  // ID is the id of the item. This field is read-only.
+  ID int `json:"id"`
+
+  // Text is the to-do item text. This field is required.
+  Text string `json:"text"`
+}
+
+// Validate implementation.
+func (i *Item) Validate() error {
+  var errs rpc.ValidationErrors
+
+  if i.Text == "" {
+    errs.Add("text", "is required")
+  }
+
+  return errs.Err()
+}
+
+// AddItemInput params.
+type AddItemInput struct {
+  // Item is the item to add. This field is required.
+  Item string `json:"item"`
+
+  // Parent is the parent item. This field is required.
+  Parent Item `json:"parent"`
+
+  // Origin is the item this item was copied from.
+  Origin Item `json:"origin"`
+
+  // Related is the related items.
+  Related []Item `json:"related"`
+}
+
+// Validate implementation.
+func (a *AddItemInput) Validate() error {
+  var errs rpc.ValidationErrors
+
+  if a.Item == "" {
+    errs.Add("item", "is required")
+  }
+
+  errs.Merge("parent", a.Parent.Validate())
+
+  if !rpc.IsZero(a.Origin) {
+    errs.Merge("origin", a.Origin.Validate())
+  }
+
+  for i, v := range a.Related {
+    errs.Merge(fmt.Sprintf("related[%d]", i), v.Validate())
+  }
+
+  return errs.Err()
+}
+
+// GetItemsOutput params.
+type GetItemsOutput struct {
+  // Items is the list of to-do items.
+  Items []Item `json:"items"`
+}
+
+// RemoveItemInput params.
+type RemoveItemInput struct {
+  // ID is the id of the item to remove.
+  ID int `json:"id"`
+}
+
+// Validate implementation.
+func (r *RemoveItemInput) Validate() error {
+  var errs rpc.ValidationErrors
+
+  return errs.Err()
+}
+
+// RemoveItemOutput params.
+type RemoveItemOutput struct {
+  // Item is the item removed.
+  Item Item `json:"item"`
+}
+
+
+// oneOf returns true if s is in the values.
+func oneOf(s string, values []string) bool {
+  for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
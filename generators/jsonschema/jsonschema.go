@@ -0,0 +1,171 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/rpc/internal/schemautil"
+	"github.com/apex/rpc/schema"
+)
+
+// Draft is the JSON Schema dialect used.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// document model.
+type document struct {
+	Schema string                   `json:"$schema,omitempty"`
+	Title  string                   `json:"title,omitempty"`
+	Defs   map[string]*schemaObject `json:"$defs,omitempty"`
+	*schemaObject
+}
+
+// schemaObject model.
+type schemaObject = schemautil.JSONSchema
+
+// Generate writes JSON Schema documents for each type, and each method input and output to dir.
+func Generate(s *schema.Schema, dir string) error {
+	// types dir
+	typesDir := filepath.Join(dir, "types")
+	if err := os.MkdirAll(typesDir, 0755); err != nil {
+		return err
+	}
+
+	// types
+	for _, t := range s.TypesSlice() {
+		err := writeFile(filepath.Join(typesDir, t.Name+".json"), func(w io.Writer) error {
+			return WriteType(w, s, t)
+		})
+		if err != nil {
+			return fmt.Errorf("generating type %q: %w", t.Name, err)
+		}
+	}
+
+	// methods dir
+	methodsDir := filepath.Join(dir, "methods")
+	if err := os.MkdirAll(methodsDir, 0755); err != nil {
+		return err
+	}
+
+	// methods
+	for _, m := range s.Methods {
+		if len(m.Inputs) > 0 {
+			err := writeFile(filepath.Join(methodsDir, m.Name+"_input.json"), func(w io.Writer) error {
+				return WriteInput(w, s, m)
+			})
+			if err != nil {
+				return fmt.Errorf("generating method %q input: %w", m.Name, err)
+			}
+		}
+
+		if len(m.Outputs) > 0 {
+			err := writeFile(filepath.Join(methodsDir, m.Name+"_output.json"), func(w io.Writer) error {
+				return WriteOutput(w, s, m)
+			})
+			if err != nil {
+				return fmt.Errorf("generating method %q output: %w", m.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteType writes the JSON Schema document for type t to w.
+func WriteType(w io.Writer, s *schema.Schema, t schema.Type) error {
+	o := object(t.Properties)
+	o.Description = t.Description
+	for _, e := range t.Examples {
+		o.Examples = append(o.Examples, e.Value)
+	}
+
+	return write(w, t.Name, o, definitions(s, t.Properties))
+}
+
+// WriteInput writes the JSON Schema document for the input of method m to w.
+func WriteInput(w io.Writer, s *schema.Schema, m schema.Method) error {
+	o := object(m.Inputs)
+	o.Description = fmt.Sprintf("The %s method input.", m.Name)
+	for _, e := range m.Examples {
+		o.Examples = append(o.Examples, e.Input)
+	}
+
+	return write(w, m.Name+"_input", o, definitions(s, m.Inputs))
+}
+
+// WriteOutput writes the JSON Schema document for the output of method m to w.
+func WriteOutput(w io.Writer, s *schema.Schema, m schema.Method) error {
+	o := object(m.Outputs)
+	o.Description = fmt.Sprintf("The %s method output.", m.Name)
+	for _, e := range m.Examples {
+		o.Examples = append(o.Examples, e.Output)
+	}
+
+	return write(w, m.Name+"_output", o, definitions(s, m.Outputs))
+}
+
+// write a document to w.
+func write(w io.Writer, title string, o *schemaObject, defs map[string]*schemaObject) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(document{
+		Schema:       Draft,
+		Title:        title,
+		Defs:         defs,
+		schemaObject: o,
+	})
+}
+
+// writeFile creates the file at path and invokes fn with it.
+func writeFile(path string, fn func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = fn(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// definitions returns the types referenced by fields, including transitive references.
+func definitions(s *schema.Schema, fields []schema.Field) map[string]*schemaObject {
+	defs := map[string]*schemaObject{}
+	seen := map[string]bool{}
+
+	var visit func(fields []schema.Field)
+	visit = func(fields []schema.Field) {
+		for _, f := range fields {
+			for _, ref := range []schema.Ref{f.Type.Ref, f.Items.Ref} {
+				if ref.Value == "" {
+					continue
+				}
+
+				t := schemautil.ResolveRef(s, ref)
+				if seen[t.Name] {
+					continue
+				}
+				seen[t.Name] = true
+
+				o := object(t.Properties)
+				o.Description = t.Description
+				defs[t.Name] = o
+				visit(t.Properties)
+			}
+		}
+	}
+
+	visit(fields)
+	return defs
+}
+
+// object returns an object schema for fields.
+func object(fields []schema.Field) *schemaObject {
+	return schemautil.Object(fields, "#/$defs/")
+}
@@ -0,0 +1,53 @@
+package jsonschema_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tj/assert"
+	"github.com/tj/go-fixture"
+
+	"github.com/apex/rpc/generators/jsonschema"
+	"github.com/apex/rpc/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	dir := t.TempDir()
+	err = jsonschema.Generate(schema, dir)
+	assert.NoError(t, err, "generating")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	assert.NoError(t, err, "listing")
+
+	for i, f := range files {
+		files[i], _ = filepath.Rel(dir, f)
+	}
+
+	assert.Equal(t, []string{
+		"methods/add_item_input.json",
+		"methods/get_items_output.json",
+		"methods/remove_item_input.json",
+		"methods/remove_item_output.json",
+		"types/item.json",
+	}, files)
+
+	b, err := os.ReadFile(filepath.Join(dir, "types", "item.json"))
+	assert.NoError(t, err, "reading")
+	fixture.Assert(t, "todo_item.json", b)
+}
+
+func TestWriteOutput(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = jsonschema.WriteOutput(&act, schema, schema.Methods[2])
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_remove_item_output.json", act.Bytes())
+}
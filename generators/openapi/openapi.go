@@ -0,0 +1,309 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/apex/rpc/internal/schemautil"
+	"github.com/apex/rpc/schema"
+)
+
+// document model.
+type document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       info                 `json:"info"`
+	Tags       []tag                `json:"tags,omitempty"`
+	Paths      map[string]*pathItem `json:"paths"`
+	Components components           `json:"components"`
+}
+
+// info model.
+type info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// tag model.
+type tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// pathItem model.
+type pathItem struct {
+	Post *operation `json:"post"`
+}
+
+// operation model.
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	RequestBody *body                `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+// body model.
+type body struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+// response model.
+type response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*mediaType `json:"content,omitempty"`
+}
+
+// mediaType model.
+type mediaType struct {
+	Schema   *schemaObject       `json:"schema"`
+	Examples map[string]*example `json:"examples,omitempty"`
+}
+
+// example model.
+type example struct {
+	Summary string      `json:"summary,omitempty"`
+	Value   interface{} `json:"value"`
+}
+
+// components model.
+type components struct {
+	Schemas   map[string]*schemaObject `json:"schemas"`
+	Responses map[string]*response     `json:"responses"`
+}
+
+// schemaObject model.
+type schemaObject = schemautil.JSONSchema
+
+// errorSchema is the error response written by rpc.WriteError.
+var errorSchema = &schemaObject{
+	Type:        "object",
+	Description: "An error response.",
+	Properties: map[string]*schemaObject{
+		"type": {
+			Type:        "string",
+			Description: "The error type, defaulting to \"internal\".",
+		},
+		"message": {
+			Type:        "string",
+			Description: "The error message.",
+		},
+		"fields": {
+			Type:        "array",
+			Description: "The field validation errors.",
+			Items: &schemaObject{
+				Type: "object",
+				Properties: map[string]*schemaObject{
+					"field": {
+						Type:        "string",
+						Description: "The JSON path of the field, such as \"items[2].text\".",
+					},
+					"message": {
+						Type:        "string",
+						Description: "The validation error message.",
+					},
+				},
+				Required: []string{"field", "message"},
+			},
+		},
+		"details": {
+			Type:        "object",
+			Description: "The structured error details.",
+		},
+		"request_id": {
+			Type:        "string",
+			Description: "The id of the request.",
+		},
+	},
+	Required: []string{"type", "message"},
+}
+
+// Generate writes an OpenAPI 3.1 document to w, with optional inclusion of private methods and types.
+func Generate(w io.Writer, s *schema.Schema, private bool) error {
+	doc := document{
+		OpenAPI: "3.1.0",
+		Info: info{
+			Title:       s.Name,
+			Description: s.Description,
+			Version:     s.Version,
+		},
+		Paths: map[string]*pathItem{},
+		Components: components{
+			Schemas: map[string]*schemaObject{
+				"Error": errorSchema,
+			},
+			Responses: map[string]*response{
+				"Error": {
+					Description: "An error response.",
+					Content: map[string]*mediaType{
+						"application/json": {
+							Schema: &schemaObject{Ref: "#/components/schemas/Error"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// groups
+	for _, g := range s.Groups {
+		doc.Tags = append(doc.Tags, tag{
+			Name:        g.Name,
+			Description: g.Summary,
+		})
+	}
+
+	// types
+	for _, t := range s.TypesSlice() {
+		if t.Private && !private {
+			continue
+		}
+
+		o := object(t.Properties)
+		o.Description = t.Description
+		for _, e := range t.Examples {
+			o.Examples = append(o.Examples, e.Value)
+		}
+		doc.Components.Schemas[t.Name] = o
+	}
+
+	// methods
+	for _, m := range s.Methods {
+		if m.Private && !private {
+			continue
+		}
+
+		op := &operation{
+			OperationID: m.Name,
+			Summary:     m.Description,
+			Responses: map[string]*response{
+				"default": {
+					Ref: "#/components/responses/Error",
+				},
+			},
+		}
+
+		if m.Group != "" {
+			op.Tags = []string{m.Group}
+		}
+
+		// inputs
+		if len(m.Inputs) > 0 {
+			name := m.Name + "_input"
+			doc.Components.Schemas[name] = object(m.Inputs)
+			op.RequestBody = &body{
+				Required: true,
+				Content: map[string]*mediaType{
+					"application/json": {
+						Schema:   &schemaObject{Ref: "#/components/schemas/" + name},
+						Examples: examples(m.Examples, true),
+					},
+				},
+			}
+		}
+
+		// outputs
+		if len(m.Outputs) > 0 && m.Stream {
+			name := m.Name + "_output"
+			doc.Components.Schemas[name] = object(m.Outputs)
+			op.Responses["200"] = &response{
+				Description: "Success, with a stream of newline-delimited objects with an output as \"result\", or a final error.",
+				Content: map[string]*mediaType{
+					"application/x-ndjson": {
+						Schema: streamLine(name),
+					},
+				},
+			}
+		} else if len(m.Outputs) > 0 {
+			name := m.Name + "_output"
+			doc.Components.Schemas[name] = object(m.Outputs)
+			op.Responses["200"] = &response{
+				Description: "Success.",
+				Content: map[string]*mediaType{
+					"application/json": {
+						Schema:   &schemaObject{Ref: "#/components/schemas/" + name},
+						Examples: examples(m.Examples, false),
+					},
+				},
+			}
+		} else {
+			op.Responses["204"] = &response{
+				Description: "Success, with no content.",
+			}
+		}
+
+		doc.Paths["/"+m.Name] = &pathItem{Post: op}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("encoding: %w", err)
+	}
+
+	return nil
+}
+
+// object returns an object schema for fields.
+func object(fields []schema.Field) *schemaObject {
+	return schemautil.Object(fields, "#/components/schemas/")
+}
+
+// streamLine returns the schema of a line of a stream of output type name,
+// which is either a result or the final error, as written by rpc.Stream.
+func streamLine(name string) *schemaObject {
+	return &schemaObject{
+		OneOf: []*schemaObject{
+			{
+				Type: "object",
+				Properties: map[string]*schemaObject{
+					"result": {Ref: "#/components/schemas/" + name},
+				},
+				Required: []string{"result"},
+			},
+			{
+				Type: "object",
+				Properties: map[string]*schemaObject{
+					"status": {
+						Type:        "integer",
+						Description: "The HTTP status code of the error.",
+					},
+					"error": {Ref: "#/components/schemas/Error"},
+				},
+				Required: []string{"error", "status"},
+			},
+		},
+	}
+}
+
+// examples returns the method examples for the input or output.
+func examples(list []schema.MethodExample, input bool) map[string]*example {
+	if len(list) == 0 {
+		return nil
+	}
+
+	m := map[string]*example{}
+	for i, e := range list {
+		name := e.Name
+		if name == "" {
+			name = fmt.Sprintf("example_%d", i+1)
+		}
+
+		v := e.Output
+		if input {
+			v = e.Input
+		}
+
+		m[name] = &example{
+			Summary: e.Description,
+			Value:   v,
+		}
+	}
+
+	return m
+}
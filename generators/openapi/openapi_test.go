@@ -0,0 +1,36 @@
+package openapi_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tj/assert"
+	"github.com/tj/go-fixture"
+
+	"github.com/apex/rpc/generators/openapi"
+	"github.com/apex/rpc/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = openapi.Generate(&act, schema, false)
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_openapi.json", act.Bytes())
+}
+
+func TestGenerate_stream(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Methods[1].Stream = true
+
+	var act bytes.Buffer
+	err = openapi.Generate(&act, s, false)
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_openapi_stream.json", act.Bytes())
+}
@@ -10,17 +10,31 @@ import (
 
 var call = `
   private function call($method, $body) {
+    $requestId = bin2hex(random_bytes(16));
     $header = "Content-type: application/json\r\n";
+    $header .= "X-Request-ID: $requestId\r\n";
+
+    foreach ($this->trace as $name => $value) {
+      $header .= "$name: $value\r\n";
+    }
 
     if (isset($this->authToken)) {
       $header .= "Authorization: Bearer $this->authToken\r\n";
     }
 
+    $content = json_encode($body);
+
+    if (isset($this->compressionThreshold) && strlen($content) >= $this->compressionThreshold) {
+      $content = gzencode($content);
+      $header .= "Content-Encoding: gzip\r\n";
+    }
+
     $options = array(
       'http' => array(
         'header'  => $header,
         'method'  => 'POST',
-        'content' => json_encode($body)
+        'content' => $content,
+        'ignore_errors' => true
       )
     );
 
@@ -28,26 +42,78 @@ var call = `
     $context = stream_context_create($options);
     $result = file_get_contents($url, false, $context);
 
-    // TODO: how to check the status code and parse error from the body?
+    // status and request id
+    $status = 0;
+    foreach ($http_response_header as $line) {
+      if (preg_match('#^HTTP/\S+ (\d+)#', $line, $m)) {
+        $status = intval($m[1]);
+      } elseif (stripos($line, 'X-Request-ID:') === 0) {
+        $requestId = trim(substr($line, 13));
+      }
+    }
+
+    if ($status >= 400) {
+      $err = json_decode($result, true);
+      throw new %sError($status, $err['type'] ?? null, $err['message'] ?? null, $requestId);
+    }
 
     return json_decode($result);
   }`
 
+var errorClass = `
+/**
+ * %sError is raised when an API call fails due to a 4xx or 5xx HTTP error.
+ */
+
+class %sError extends Exception {
+  public $status;
+  public $type;
+  public $requestId;
+
+  public function __construct($status, $type, $message, $requestId) {
+    parent::__construct($message ?? "$status response");
+    $this->status = $status;
+    $this->type = $type;
+    $this->requestId = $requestId;
+  }
+}
+`
+
 var class = `
 class %s {
   protected $url;
   protected $authToken;
+  protected $compressionThreshold;
+  protected $trace = array();
 
   /**
    * Create a new API client.
    *
    * @param string $url The endpoint URL.
    * @param string $authToken The authentication token [optional].
+   * @param int $compressionThreshold The minimum size in bytes of request bodies compressed with gzip [optional].
    */
 
-  public function __construct($url, $authToken = null) {
+  public function __construct($url, $authToken = null, $compressionThreshold = null) {
     $this->url = $url;
     $this->authToken = $authToken;
+    $this->compressionThreshold = $compressionThreshold;
+  }
+
+  /**
+   * Return a copy of the client forwarding the W3C traceparent and tracestate header values.
+   *
+   * @param string $traceparent The traceparent header value.
+   * @param string $tracestate The tracestate header value [optional].
+   */
+
+  public function withTrace($traceparent, $tracestate = null) {
+    $client = clone $this;
+    $client->trace = array('traceparent' => $traceparent);
+    if (isset($tracestate)) {
+      $client->trace['tracestate'] = $tracestate;
+    }
+    return $client;
   }
 `
 
@@ -58,6 +124,7 @@ func Generate(w io.Writer, s *schema.Schema, className string) error {
 	out(w, "<?php\n")
 	out(w, "// Do not edit, this file was generated by github.com/apex/rpc.\n")
 
+	out(w, errorClass, className, className)
 	out(w, class, className)
 
 	for _, m := range s.Methods {
@@ -96,7 +163,7 @@ func Generate(w io.Writer, s *schema.Schema, className string) error {
 		out(w, "  }\n")
 	}
 
-	out(w, "%s\n", call)
+	out(w, call+"\n", className)
 	out(w, "}\n")
 
 	return nil
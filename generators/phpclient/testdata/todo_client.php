@@ -1,20 +1,57 @@
 <?php
 // Do not edit, this file was generated by github.com/apex/rpc.
 
+/**
+ * ClientError is raised when an API call fails due to a 4xx or 5xx HTTP error.
+ */
+
+class ClientError extends Exception {
+  public $status;
+  public $type;
+  public $requestId;
+
+  public function __construct($status, $type, $message, $requestId) {
+    parent::__construct($message ?? "$status response");
+    $this->status = $status;
+    $this->type = $type;
+    $this->requestId = $requestId;
+  }
+}
+
 class Client {
   protected $url;
   protected $authToken;
+  protected $compressionThreshold;
+  protected $trace = array();
 
   /**
    * Create a new API client.
    *
    * @param string $url The endpoint URL.
    * @param string $authToken The authentication token [optional].
+   * @param int $compressionThreshold The minimum size in bytes of request bodies compressed with gzip [optional].
    */
 
-  public function __construct($url, $authToken = null) {
+  public function __construct($url, $authToken = null, $compressionThreshold = null) {
     $this->url = $url;
     $this->authToken = $authToken;
+    $this->compressionThreshold = $compressionThreshold;
+  }
+
+  /**
+   * Return a copy of the client forwarding the W3C traceparent and tracestate header values.
+   *
+   * @param string $traceparent The traceparent header value.
+   * @param string $tracestate The tracestate header value [optional].
+   */
+
+  public function withTrace($traceparent, $tracestate = null) {
+    $client = clone $this;
+    $client->trace = array('traceparent' => $traceparent);
+    if (isset($tracestate)) {
+      $client->trace['tracestate'] = $tracestate;
+    }
+    return $client;
   }
 
   /**
@@ -46,17 +83,31 @@ class Client {
   }
 
   private function call($method, $body) {
+    $requestId = bin2hex(random_bytes(16));
     $header = "Content-type: application/json\r\n";
+    $header .= "X-Request-ID: $requestId\r\n";
+
+    foreach ($this->trace as $name => $value) {
+      $header .= "$name: $value\r\n";
+    }
 
     if (isset($this->authToken)) {
       $header .= "Authorization: Bearer $this->authToken\r\n";
     }
 
+    $content = json_encode($body);
+
+    if (isset($this->compressionThreshold) && strlen($content) >= $this->compressionThreshold) {
+      $content = gzencode($content);
+      $header .= "Content-Encoding: gzip\r\n";
+    }
+
     $options = array(
       'http' => array(
         'header'  => $header,
         'method'  => 'POST',
-        'content' => json_encode($body)
+        'content' => $content,
+        'ignore_errors' => true
       )
     );
 
@@ -64,7 +115,20 @@ class Client {
     $context = stream_context_create($options);
     $result = file_get_contents($url, false, $context);
 
-    // TODO: how to check the status code and parse error from the body?
+    // status and request id
+    $status = 0;
+    foreach ($http_response_header as $line) {
+      if (preg_match('#^HTTP/\S+ (\d+)#', $line, $m)) {
+        $status = intval($m[1]);
+      } elseif (stripos($line, 'X-Request-ID:') === 0) {
+        $requestId = trim(substr($line, 13));
+      }
+    }
+
+    if ($status >= 400) {
+      $err = json_decode($result, true);
+      throw new ClientError($status, $err['type'] ?? null, $err['message'] ?? null, $requestId);
+    }
 
     return json_decode($result);
   }
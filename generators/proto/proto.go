@@ -0,0 +1,252 @@
+package proto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apex/rpc/internal/format"
+	"github.com/apex/rpc/internal/schemautil"
+	"github.com/apex/rpc/schema"
+)
+
+// Lock tracks the field numbers assigned to each message, so that
+// regenerating the .proto file never renumbers existing fields.
+type Lock struct {
+	Messages map[string]*MessageLock `json:"messages"`
+}
+
+// MessageLock tracks the field numbers of a message. Fields removed
+// from the schema remain in the lock so their numbers are reserved.
+type MessageLock struct {
+	Fields map[string]int `json:"fields"`
+}
+
+// number returns the field number for name, assigning the next available number if necessary.
+func (m *MessageLock) number(name string) int {
+	if n, ok := m.Fields[name]; ok {
+		return n
+	}
+
+	max := 0
+	for _, n := range m.Fields {
+		if n > max {
+			max = n
+		}
+	}
+
+	m.Fields[name] = max + 1
+	return max + 1
+}
+
+// message returns the lock for message name, creating it if necessary.
+func (l *Lock) message(name string) *MessageLock {
+	if l.Messages == nil {
+		l.Messages = map[string]*MessageLock{}
+	}
+
+	m := l.Messages[name]
+	if m == nil {
+		m = &MessageLock{}
+		l.Messages[name] = m
+	}
+
+	if m.Fields == nil {
+		m.Fields = map[string]int{}
+	}
+
+	return m
+}
+
+// LoadLock returns a lock loaded from path, or an empty lock if it does not exist.
+func LoadLock(path string) (*Lock, error) {
+	var l Lock
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &l, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &l)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return &l, nil
+}
+
+// Save writes the lock to path.
+func (l *Lock) Save(path string) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Generate writes the Protocol Buffers definitions to w, assigning field numbers using lock.
+func Generate(w io.Writer, s *schema.Schema, pkg, goPackage string, lock *Lock) error {
+	out := fmt.Fprintf
+
+	var body strings.Builder
+	imports := map[string]bool{}
+
+	// types
+	for _, t := range s.TypesSlice() {
+		writeMessage(&body, s, format.GoName(t.Name), t.Description, t.Properties, lock, imports)
+	}
+
+	// methods
+	for _, m := range s.Methods {
+		name := format.GoName(m.Name)
+		if len(m.Inputs) > 0 {
+			writeMessage(&body, s, name+"Input", "params.", m.Inputs, lock, imports)
+		}
+		if len(m.Outputs) > 0 {
+			writeMessage(&body, s, name+"Output", "params.", m.Outputs, lock, imports)
+		}
+	}
+
+	// service
+	service := format.GoName(s.Name) + "Service"
+	out(&body, "// %s %s\n", service, s.Description)
+	out(&body, "service %s {\n", service)
+	for i, m := range s.Methods {
+		name := format.GoName(m.Name)
+
+		in := name + "Input"
+		if len(m.Inputs) == 0 {
+			in = "google.protobuf.Empty"
+			imports["google/protobuf/empty.proto"] = true
+		}
+
+		res := name + "Output"
+		if len(m.Outputs) == 0 {
+			res = "google.protobuf.Empty"
+			imports["google/protobuf/empty.proto"] = true
+		}
+
+		if m.Stream {
+			res = "stream " + res
+		}
+
+		if i > 0 {
+			out(&body, "\n")
+		}
+		out(&body, "  // %s %s\n", name, m.Description)
+		out(&body, "  rpc %s(%s) returns (%s);\n", name, in, res)
+	}
+	out(&body, "}\n")
+
+	// header
+	out(w, "// Do not edit, this file was generated by github.com/apex/rpc.\n\n")
+	out(w, "syntax = \"proto3\";\n\n")
+	out(w, "package %s;\n\n", pkg)
+
+	if goPackage != "" {
+		out(w, "option go_package = %q;\n\n", goPackage)
+	}
+
+	if len(imports) > 0 {
+		var list []string
+		for path := range imports {
+			list = append(list, path)
+		}
+		sort.Strings(list)
+
+		for _, path := range list {
+			out(w, "import %q;\n", path)
+		}
+		out(w, "\n")
+	}
+
+	out(w, "%s", body.String())
+	return nil
+}
+
+// writeMessage writes a message definition to w.
+func writeMessage(w io.Writer, s *schema.Schema, name, desc string, fields []schema.Field, lock *Lock, imports map[string]bool) {
+	out := fmt.Fprintf
+	l := lock.message(name)
+
+	out(w, "// %s %s\n", name, desc)
+	out(w, "message %s {\n", name)
+
+	present := map[string]bool{}
+	for i, f := range fields {
+		present[f.Name] = true
+		if i > 0 {
+			out(w, "\n")
+		}
+		out(w, "  // %s is %s%s\n", f.Name, f.Description, schemautil.FormatExtra(f))
+		out(w, "  %s %s = %d;\n", protoType(s, f, imports), f.Name, l.number(f.Name))
+	}
+
+	// reserve removed fields
+	var removed []string
+	for field := range l.Fields {
+		if !present[field] {
+			removed = append(removed, field)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return l.Fields[removed[i]] < l.Fields[removed[j]]
+	})
+
+	if len(removed) > 0 {
+		var names, numbers []string
+		for _, field := range removed {
+			names = append(names, fmt.Sprintf("%q", field))
+			numbers = append(numbers, fmt.Sprintf("%d", l.Fields[field]))
+		}
+
+		if len(fields) > 0 {
+			out(w, "\n")
+		}
+		out(w, "  reserved %s;\n", strings.Join(numbers, ", "))
+		out(w, "  reserved %s;\n", strings.Join(names, ", "))
+	}
+
+	out(w, "}\n\n")
+}
+
+// protoType returns a Protocol Buffers equivalent type for field f.
+func protoType(s *schema.Schema, f schema.Field, imports map[string]bool) string {
+	// ref
+	if ref := f.Type.Ref.Value; ref != "" {
+		t := schemautil.ResolveRef(s, f.Type.Ref)
+		return format.GoName(t.Name)
+	}
+
+	// type
+	switch f.Type.Type {
+	case schema.String:
+		return "string"
+	case schema.Int:
+		return "int64"
+	case schema.Bool:
+		return "bool"
+	case schema.Float:
+		return "double"
+	case schema.Timestamp:
+		imports["google/protobuf/timestamp.proto"] = true
+		return "google.protobuf.Timestamp"
+	case schema.Object:
+		imports["google/protobuf/struct.proto"] = true
+		return "google.protobuf.Struct"
+	case schema.Array:
+		return "repeated " + protoType(s, schema.Field{
+			Type: schema.TypeObject(f.Items),
+		}, imports)
+	default:
+		panic("unhandled type")
+	}
+}
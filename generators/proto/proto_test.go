@@ -0,0 +1,80 @@
+package proto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tj/assert"
+	"github.com/tj/go-fixture"
+
+	"github.com/apex/rpc/generators/proto"
+	"github.com/apex/rpc/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = proto.Generate(&act, schema, "todo", "", &proto.Lock{})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo.proto", act.Bytes())
+}
+
+func TestGenerate_lock(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var lock proto.Lock
+	var act bytes.Buffer
+	err = proto.Generate(&act, s, "todo", "", &lock)
+	assert.NoError(t, err, "generating")
+	assert.Equal(t, map[string]int{"created_at": 1, "id": 2, "text": 3}, lock.Messages["Item"].Fields)
+
+	// remove "id" and add "done"
+	item := s.Types["item"]
+	item.Properties = []schema.Field{
+		item.Properties[0],
+		item.Properties[2],
+		{Name: "done", Description: "whether the item is complete.", Type: schema.TypeObject{Type: schema.Bool}},
+	}
+	s.Types["item"] = item
+
+	act.Reset()
+	err = proto.Generate(&act, s, "todo", "github.com/apex/todo/api", &lock)
+	assert.NoError(t, err, "generating")
+	assert.Equal(t, map[string]int{"created_at": 1, "id": 2, "text": 3, "done": 4}, lock.Messages["Item"].Fields)
+
+	fixture.Assert(t, "todo_lock.proto", act.Bytes())
+}
+
+func TestGenerate_stream(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Methods[1].Stream = true
+
+	var act bytes.Buffer
+	err = proto.Generate(&act, s, "todo", "", &proto.Lock{})
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_stream.proto", act.Bytes())
+}
+
+func TestGenerate_nullLock(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	lock := proto.Lock{Messages: map[string]*proto.MessageLock{
+		"Item":           {},
+		"AddItemInput":   nil,
+		"GetItemsOutput": {Fields: map[string]int{"items": 1}},
+	}}
+
+	var act bytes.Buffer
+	err = proto.Generate(&act, s, "todo", "", &lock)
+	assert.NoError(t, err, "generating")
+	assert.Equal(t, map[string]int{"created_at": 1, "id": 2, "text": 3}, lock.Messages["Item"].Fields)
+	assert.Equal(t, map[string]int{"item": 1}, lock.Messages["AddItemInput"].Fields)
+}
@@ -4,6 +4,8 @@
 require 'net/http'
 require 'net/https'
 require 'json'
+require 'securerandom'
+require 'zlib'
 
 module Todo
   class Client
@@ -12,11 +14,13 @@ module Todo
       attr_reader :type
       attr_reader :message
       attr_reader :status
+      attr_reader :request_id
 
-      def initialize(status, type = nil, message = nil)
+      def initialize(status, type = nil, message = nil, request_id = nil)
         @status = status
         @type = type
         @message = message
+        @request_id = request_id
       end
 
       def to_s
@@ -28,10 +32,21 @@ module Todo
       end
     end
 
-    # Initialize the client with API endpoint URL and optional authentication token.
-    def initialize(url, auth_token = nil)
+    # Initialize the client with API endpoint URL, optional authentication token,
+    # and optional minimum size in bytes of request bodies compressed with gzip.
+    def initialize(url, auth_token = nil, compression_threshold = nil)
       @url = url
       @auth_token = auth_token
+      @compression_threshold = compression_threshold
+    end
+
+    # Returns a copy of the client forwarding the W3C traceparent and tracestate header values.
+    def with_trace(traceparent, tracestate = nil)
+      trace = { "traceparent" => traceparent }
+      trace["tracestate"] = tracestate if tracestate
+      client = dup
+      client.instance_variable_set(:@trace, trace)
+      client
     end
 
     # Adds an item to the list.
@@ -60,22 +75,32 @@ module Todo
     # call an API method with optional input parameters.
     def call(method, params = nil)
       url = @url + "/" + method
-      header = { "Content-Type" => "application/json" }
+      request_id = SecureRandom.hex(16)
+      header = { "Content-Type" => "application/json", "X-Request-ID" => request_id }
+  
+      header.merge!(@trace) if @trace
   
       if @auth_token
         header["Authorization"] = "Bearer #{@auth_token}"
       end
   
-      res = Net::HTTP.post URI(url), params.to_json, header
+      body = params.to_json
+      if @compression_threshold && body.bytesize >= @compression_threshold
+        body = Zlib.gzip(body)
+        header["Content-Encoding"] = "gzip"
+      end
+  
+      res = Net::HTTP.post URI(url), body, header
       status = res.code.to_i
   
       if status >= 400
+        request_id = res["X-Request-ID"] || request_id
         begin
           body = JSON.parse(res.body)
         rescue
-          raise Error.new(status)
+          raise Error.new(status, nil, nil, request_id)
         end
-        raise Error.new(status, body["type"], body["message"])
+        raise Error.new(status, body["type"], body["message"], request_id)
       end
   
       res.body
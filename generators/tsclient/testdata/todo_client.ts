@@ -6,36 +6,120 @@ const fetch = (typeof window == 'undefined' || window.fetch == null)
   : window.fetch
 
 /**
- * ClientError is an API client error providing the HTTP status code and error type.
+ * FieldError is a field validation error, with the JSON path of the field.
  */
 
-class ClientError extends Error {
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+/**
+ * ClientError is an API client error providing the HTTP status code and error type,
+ * and field validation errors and details when present.
+ */
+
+export class ClientError extends Error {
   status: number;
   type?: string;
+  fields?: FieldError[];
+  details?: Record<string, any>;
+  retryAfter?: number;
+  requestId?: string;
 
-  constructor(status: number, message?: string, type?: string) {
+  constructor(status: number, message?: string, type?: string, fields?: FieldError[], details?: Record<string, any>) {
     super(message)
     this.status = status
     this.type = type
+    this.fields = fields
+    this.details = details
   }
 }
 
 /**
- * Call method with params via a POST request.
+ * Codec is used to encode requests and decode responses of a media type other than JSON.
+ */
+
+export interface Codec {
+  contentType: string;
+  encode(value: any): Uint8Array;
+  decode(body: Uint8Array): any;
+}
+
+/**
+ * Compress body with gzip.
+ */
+
+async function gzip(body: string | Uint8Array): Promise<Uint8Array> {
+  const stream = new Blob([body]).stream().pipeThrough(new CompressionStream('gzip'))
+  return new Uint8Array(await new Response(stream).arrayBuffer())
+}
+
+/**
+ * Return a new random request ID, when supported by the runtime.
  */
 
-async function call(url: string, method: string, authToken?: string, params?: any): Promise<string> {
+function newRequestId(): string | undefined {
+  const crypto = (globalThis as any).crypto
+  return crypto && crypto.randomUUID
+    ? crypto.randomUUID()
+    : undefined
+}
+
+/**
+ * Call method with params via a POST request, returning the response body.
+ */
+
+async function call(url: string, method: string, authToken?: string, params?: any, codec?: Codec, compressionThreshold?: number, trace?: Record<string, string>): Promise<any> {
+  const res = await send(url, method, authToken, params, codec, compressionThreshold, trace)
+  return codec
+    ? new Uint8Array(await res.arrayBuffer())
+    : res.text()
+}
+
+/**
+ * Send a POST request for method with params, returning the response, or throwing a
+ * ClientError for error responses. The Accept header defaults to the codec media type.
+ */
+
+async function send(url: string, method: string, authToken?: string, params?: any, codec?: Codec, compressionThreshold?: number, trace?: Record<string, string>, accept?: string): Promise<Response> {
   const headers: Record<string, string> = {
-    'Content-Type': 'application/json'
+    'Content-Type': codec ? codec.contentType : 'application/json'
+  }
+
+  const type = accept || (codec && codec.contentType)
+  if (type) {
+    headers['Accept'] = type
   }
   
+  const requestId = newRequestId()
+  if (requestId != null) {
+    headers['X-Request-ID'] = requestId
+  }
+
+  if (trace != null) {
+    Object.assign(headers, trace)
+  }
+
   if (authToken != null) {
     headers['Authorization'] = `Bearer ${authToken}`
   }
   
+  let body: string | Uint8Array | undefined
+  if (params !== undefined) {
+    body = codec ? codec.encode(params) : JSON.stringify(params)
+
+    // compare the encoded size in bytes, not the string length
+    const size = typeof body == 'string' ? new TextEncoder().encode(body).length : body.length
+    if (compressionThreshold != null && compressionThreshold > 0 && size >= compressionThreshold) {
+      body = await gzip(body)
+      headers['Content-Encoding'] = 'gzip'
+    }
+  }
+
   const res = await fetch(url + '/' + method, {
     method: 'POST',
-    body: JSON.stringify(params),
+    body,
     headers
   })
 
@@ -44,15 +128,158 @@ async function call(url: string, method: string, authToken?: string, params?: an
   if (res.status >= 300) {
     let err
     try {
-      const { type, message } = await res.json()
-      err = new ClientError(res.status, message, type)
+      const { type, message, fields, details } = await res.json()
+      err = new ClientError(res.status, message, type, fields, details)
     } catch {
       err = new ClientError(res.status, res.statusText)
     }
+
+    err.requestId = res.headers.get('X-Request-ID') || requestId
+
+    // seconds after which the request may be retried
+    const retryAfter = res.headers.get('Retry-After')
+    if (retryAfter != null) {
+      err.retryAfter = parseInt(retryAfter, 10)
+    }
+
     throw err
   }
 
-  return res.text()
+  return res
+}
+
+/**
+ * Return the ClientError of a batch or stream result.
+ */
+
+function resultError(result: any): ClientError {
+  const { type, message, fields, details, request_id } = result.error
+  const err = new ClientError(result.status, message, type, fields, details)
+  err.requestId = request_id
+  return err
+}
+
+/**
+ * BatchCall is a call in a batch, with its output or error set by Batch.send().
+ */
+
+export class BatchCall<T> {
+  method: string
+  input?: any
+  output?: T
+  error?: ClientError
+
+  constructor(method: string, input?: any) {
+    this.method = method
+    this.input = input
+  }
+}
+
+/**
+ * Notification is a notification sent by the server over a connection.
+ */
+
+export interface Notification {
+  event: string;
+  data?: any;
+}
+
+/**
+ * Open a WebSocket connection to the API, resolving when it is open. Browsers
+ * cannot set headers, so the auth token is passed as the access_token parameter.
+ */
+
+function openWebSocket(url: string, authToken?: string): Promise<WebSocket> {
+  let wsURL = url.replace(/^http/, 'ws') + '/_websocket'
+  if (authToken != null) {
+    wsURL += '?access_token=' + encodeURIComponent(authToken)
+  }
+
+  return new Promise((resolve, reject) => {
+    const ws = new WebSocket(wsURL)
+    ws.onopen = () => resolve(ws)
+    ws.onerror = () => reject(new Error('WebSocket connection failed'))
+  })
+}
+
+/**
+ * isBadRequest returns true if err is a bad request error.
+ */
+
+export function isBadRequest(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'bad_request'
+}
+
+/**
+ * isInvalid returns true if err is a validation error.
+ */
+
+export function isInvalid(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'invalid'
+}
+
+/**
+ * isUnauthorized returns true if err is an unauthorized error.
+ */
+
+export function isUnauthorized(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'unauthorized'
+}
+
+/**
+ * isForbidden returns true if err is a forbidden error.
+ */
+
+export function isForbidden(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'forbidden'
+}
+
+/**
+ * isNotFound returns true if err is a not found error.
+ */
+
+export function isNotFound(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'not_found'
+}
+
+/**
+ * isConflict returns true if err is a conflict error.
+ */
+
+export function isConflict(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'conflict'
+}
+
+/**
+ * isPreconditionFailed returns true if err is a precondition failed error.
+ */
+
+export function isPreconditionFailed(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'precondition_failed'
+}
+
+/**
+ * isRateLimited returns true if err is a rate limited error.
+ */
+
+export function isRateLimited(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'rate_limited'
+}
+
+/**
+ * isInternal returns true if err is an internal server error.
+ */
+
+export function isInternal(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'internal'
+}
+
+/**
+ * isUnavailable returns true if err is a service unavailable error.
+ */
+
+export function isUnavailable(err: any): err is ClientError {
+  return err instanceof ClientError && err.type == 'unavailable'
 }
 
 
@@ -66,14 +293,32 @@ export class Client {
 
   private url: string
   private authToken?: string
+  private codec?: Codec
+  private compressionThreshold?: number
+  private trace?: Record<string, string>
 
   /**
    * Initialize.
    */
 
-  constructor(params: { url: string, authToken?: string }) {
+  constructor(params: { url: string, authToken?: string, codec?: Codec, compressionThreshold?: number }) {
     this.url = params.url
     this.authToken = params.authToken
+    this.codec = params.codec
+    this.compressionThreshold = params.compressionThreshold
+  }
+
+  /**
+   * Return a copy of the client forwarding the W3C traceparent and tracestate header values.
+   */
+
+  withTrace(traceparent: string, tracestate?: string): Client {
+    const client: Client = Object.assign(Object.create(Client.prototype), this)
+    client.trace = { traceparent }
+    if (tracestate != null) {
+      client.trace.tracestate = tracestate
+    }
+    return client
   }
 
   /**
@@ -86,12 +331,39 @@ export class Client {
       : value
   }
 
+  /**
+   * Decode a response body using the codec, defaulting to JSON.
+   */
+
+  private decode(body: any): any {
+    return this.codec
+      ? this.codec.decode(body)
+      : JSON.parse(body, this.decoder)
+  }
+
+  /**
+   * Return a new batch of calls, which requires the server to enable batching.
+   */
+
+  batch(): Batch {
+    return new Batch(this.url, this.authToken, this.compressionThreshold, this.trace, this.decoder)
+  }
+
+  /**
+   * Open a WebSocket connection for calls and notifications, which requires the server to enable WebSockets.
+   */
+
+  async connect(onNotification?: (n: Notification) => void): Promise<Connection> {
+    const ws = await openWebSocket(this.url, this.authToken)
+    return new Connection(ws, onNotification, this.decoder)
+  }
+
   /**
    * addItem: adds an item to the list.
    */
 
   async addItem(params: AddItemInput) {
-    await call(this.url, 'add_item', this.authToken, params)
+    await call(this.url, 'add_item', this.authToken, params, this.codec, this.compressionThreshold, this.trace)
   }
 
   /**
@@ -99,8 +371,8 @@ export class Client {
    */
 
   async getItems(): Promise<GetItemsOutput> {
-    let res = await call(this.url, 'get_items', this.authToken)
-    let out: GetItemsOutput = JSON.parse(res, this.decoder)
+    let res = await call(this.url, 'get_items', this.authToken, undefined, this.codec, this.compressionThreshold, this.trace)
+    let out: GetItemsOutput = this.decode(res)
     return out
   }
 
@@ -109,9 +381,187 @@ export class Client {
    */
 
   async removeItem(params: RemoveItemInput): Promise<RemoveItemOutput> {
-    let res = await call(this.url, 'remove_item', this.authToken, params)
-    let out: RemoveItemOutput = JSON.parse(res, this.decoder)
+    let res = await call(this.url, 'remove_item', this.authToken, params, this.codec, this.compressionThreshold, this.trace)
+    let out: RemoveItemOutput = this.decode(res)
     return out
   }
 
 }
+
+/**
+ * Batch is a batch of calls sent in a single request with send().
+ */
+
+export class Batch {
+
+  private calls: BatchCall<any>[] = []
+
+  /**
+   * Initialize.
+   */
+
+  constructor(private url: string, private authToken?: string, private compressionThreshold?: number, private trace?: Record<string, string>, private decoder?: (key: any, value: any) => any) {}
+
+  /**
+   * Add a call to the batch.
+   */
+
+  private add<T>(method: string, input?: any): BatchCall<T> {
+    const c = new BatchCall<T>(method, input)
+    this.calls.push(c)
+    return c
+  }
+
+  /**
+   * Send the calls of the batch in a single request, setting their outputs and errors.
+   * The promise is rejected with the error of the batch request itself.
+   */
+
+  async send(): Promise<void> {
+    if (this.calls.length == 0) {
+      return
+    }
+
+    const calls = this.calls.map(({ method, input }) => ({ method, input }))
+    const res = await call(this.url, '_batch', this.authToken, calls, undefined, this.compressionThreshold, this.trace)
+    const results: any[] = JSON.parse(res, this.decoder)
+
+    results.forEach((result, i) => {
+      const c = this.calls[i]
+      if (result.error != null) {
+        c.error = resultError(result)
+      } else {
+        c.output = result.result
+      }
+    })
+  }
+
+  /**
+   * addItem: add a call of add_item to the batch.
+   */
+
+  addItem(params: AddItemInput): BatchCall<void> {
+    return this.add('add_item', params)
+  }
+
+  /**
+   * getItems: add a call of get_items to the batch.
+   */
+
+  getItems(): BatchCall<GetItemsOutput> {
+    return this.add('get_items')
+  }
+
+  /**
+   * removeItem: add a call of remove_item to the batch.
+   */
+
+  removeItem(params: RemoveItemInput): BatchCall<RemoveItemOutput> {
+    return this.add('remove_item', params)
+  }
+
+}
+
+/**
+ * Connection is a WebSocket connection multiplexing calls, see Client.connect().
+ */
+
+export class Connection {
+
+  private id = 0
+  private pending = new Map<number, { resolve: (value: any) => void, reject: (err: Error) => void }>()
+
+  /**
+   * Initialize.
+   */
+
+  constructor(private ws: WebSocket, private onNotification?: (n: Notification) => void, private decoder?: (key: any, value: any) => any) {
+    ws.onmessage = (e) => this.receive(e.data)
+    ws.onclose = () => this.fail(new Error('connection closed'))
+  }
+
+  /**
+   * Close the connection, rejecting pending calls.
+   */
+
+  close() {
+    this.ws.close()
+  }
+
+  /**
+   * Receive a result or notification message.
+   */
+
+  private receive(data: string) {
+    const msg = JSON.parse(data, this.decoder)
+
+    if (msg.event != null) {
+      if (this.onNotification) {
+        this.onNotification({ event: msg.event, data: msg.data })
+      }
+      return
+    }
+
+    const p = this.pending.get(msg.id)
+    if (p == null) {
+      return
+    }
+
+    this.pending.delete(msg.id)
+    if (msg.error != null) {
+      p.reject(resultError(msg))
+    } else {
+      p.resolve(msg.result)
+    }
+  }
+
+  /**
+   * Reject pending calls with err.
+   */
+
+  private fail(err: Error) {
+    this.pending.forEach(p => p.reject(err))
+    this.pending.clear()
+  }
+
+  /**
+   * Call method with input, resolving with its result.
+   */
+
+  private call(method: string, input?: any): Promise<any> {
+    if (this.ws.readyState != WebSocket.OPEN) {
+      return Promise.reject(new Error('connection closed'))
+    }
+
+    const id = ++this.id
+    return new Promise((resolve, reject) => {
+      this.pending.set(id, { resolve, reject })
+      this.ws.send(JSON.stringify({ id, method, input }))
+    })
+  }
+
+  /**
+   * addItem: adds an item to the list.
+   */
+
+  async addItem(params: AddItemInput) {
+    await this.call('add_item', params)
+  }
+
+  /**
+   * getItems: returns all items in the list.
+   */
+
+  async getItems(): Promise<GetItemsOutput> {
+    return this.call('get_items')
+  }
+
+  /**
+   * removeItem: removes an item from the to-do list.
+   */
+
+  async removeItem(params: RemoveItemInput): Promise<RemoveItemOutput> {
+    return this.call('remove_item', params)
+  }
+
+}
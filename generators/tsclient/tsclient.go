@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/apex/rpc/internal/catalog"
 	"github.com/apex/rpc/internal/format"
 	"github.com/apex/rpc/schema"
 )
@@ -17,36 +18,120 @@ const fetch = (typeof window == 'undefined' || window.fetch == null)
 `
 
 var call = `/**
- * ClientError is an API client error providing the HTTP status code and error type.
+ * FieldError is a field validation error, with the JSON path of the field.
  */
 
-class ClientError extends Error {
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+/**
+ * ClientError is an API client error providing the HTTP status code and error type,
+ * and field validation errors and details when present.
+ */
+
+export class ClientError extends Error {
   status: number;
   type?: string;
+  fields?: FieldError[];
+  details?: Record<string, any>;
+  retryAfter?: number;
+  requestId?: string;
 
-  constructor(status: number, message?: string, type?: string) {
+  constructor(status: number, message?: string, type?: string, fields?: FieldError[], details?: Record<string, any>) {
     super(message)
     this.status = status
     this.type = type
+    this.fields = fields
+    this.details = details
   }
 }
 
 /**
- * Call method with params via a POST request.
+ * Codec is used to encode requests and decode responses of a media type other than JSON.
+ */
+
+export interface Codec {
+  contentType: string;
+  encode(value: any): Uint8Array;
+  decode(body: Uint8Array): any;
+}
+
+/**
+ * Compress body with gzip.
  */
 
-async function call(url: string, method: string, authToken?: string, params?: any): Promise<string> {
+async function gzip(body: string | Uint8Array): Promise<Uint8Array> {
+  const stream = new Blob([body]).stream().pipeThrough(new CompressionStream('gzip'))
+  return new Uint8Array(await new Response(stream).arrayBuffer())
+}
+
+/**
+ * Return a new random request ID, when supported by the runtime.
+ */
+
+function newRequestId(): string | undefined {
+  const crypto = (globalThis as any).crypto
+  return crypto && crypto.randomUUID
+    ? crypto.randomUUID()
+    : undefined
+}
+
+/**
+ * Call method with params via a POST request, returning the response body.
+ */
+
+async function call(url: string, method: string, authToken?: string, params?: any, codec?: Codec, compressionThreshold?: number, trace?: Record<string, string>): Promise<any> {
+  const res = await send(url, method, authToken, params, codec, compressionThreshold, trace)
+  return codec
+    ? new Uint8Array(await res.arrayBuffer())
+    : res.text()
+}
+
+/**
+ * Send a POST request for method with params, returning the response, or throwing a
+ * ClientError for error responses. The Accept header defaults to the codec media type.
+ */
+
+async function send(url: string, method: string, authToken?: string, params?: any, codec?: Codec, compressionThreshold?: number, trace?: Record<string, string>, accept?: string): Promise<Response> {
   const headers: Record<string, string> = {
-    'Content-Type': 'application/json'
+    'Content-Type': codec ? codec.contentType : 'application/json'
+  }
+
+  const type = accept || (codec && codec.contentType)
+  if (type) {
+    headers['Accept'] = type
   }
   
+  const requestId = newRequestId()
+  if (requestId != null) {
+    headers['X-Request-ID'] = requestId
+  }
+
+  if (trace != null) {
+    Object.assign(headers, trace)
+  }
+
   if (authToken != null) {
     headers['Authorization'] = ` + "`Bearer ${authToken}`" + `
   }
   
+  let body: string | Uint8Array | undefined
+  if (params !== undefined) {
+    body = codec ? codec.encode(params) : JSON.stringify(params)
+
+    // compare the encoded size in bytes, not the string length
+    const size = typeof body == 'string' ? new TextEncoder().encode(body).length : body.length
+    if (compressionThreshold != null && compressionThreshold > 0 && size >= compressionThreshold) {
+      body = await gzip(body)
+      headers['Content-Encoding'] = 'gzip'
+    }
+  }
+
   const res = await fetch(url + '/' + method, {
     method: 'POST',
-    body: JSON.stringify(params),
+    body,
     headers
   })
 
@@ -55,15 +140,116 @@ async function call(url: string, method: string, authToken?: string, params?: an
   if (res.status >= 300) {
     let err
     try {
-      const { type, message } = await res.json()
-      err = new ClientError(res.status, message, type)
+      const { type, message, fields, details } = await res.json()
+      err = new ClientError(res.status, message, type, fields, details)
     } catch {
       err = new ClientError(res.status, res.statusText)
     }
+
+    err.requestId = res.headers.get('X-Request-ID') || requestId
+
+    // seconds after which the request may be retried
+    const retryAfter = res.headers.get('Retry-After')
+    if (retryAfter != null) {
+      err.retryAfter = parseInt(retryAfter, 10)
+    }
+
     throw err
   }
 
-  return res.text()
+  return res
+}
+
+/**
+ * Return the ClientError of a batch or stream result.
+ */
+
+function resultError(result: any): ClientError {
+  const { type, message, fields, details, request_id } = result.error
+  const err = new ClientError(result.status, message, type, fields, details)
+  err.requestId = request_id
+  return err
+}`
+
+var stream = `/**
+ * Read the outputs of a newline-delimited JSON response stream,
+ * throwing the ClientError which ended it, if any.
+ */
+
+async function* readStream(res: Response, reviver?: (key: any, value: any) => any): AsyncGenerator<any> {
+  const reader = res.body!.pipeThrough(new TextDecoderStream()).getReader()
+  let buf = ''
+
+  try {
+    while (true) {
+      const { value, done } = await reader.read()
+      if (done) {
+        break
+      }
+
+      buf += value
+      let i
+      while ((i = buf.indexOf('\n')) >= 0) {
+        const line = buf.slice(0, i).trim()
+        buf = buf.slice(i + 1)
+        if (line == '') {
+          continue
+        }
+
+        const msg = JSON.parse(line, reviver)
+        if (msg.error != null) {
+          throw resultError(msg)
+        }
+
+        yield msg.result
+      }
+    }
+  } finally {
+    await reader.cancel()
+  }
+}`
+
+var batchCall = `/**
+ * BatchCall is a call in a batch, with its output or error set by Batch.send().
+ */
+
+export class BatchCall<T> {
+  method: string
+  input?: any
+  output?: T
+  error?: ClientError
+
+  constructor(method: string, input?: any) {
+    this.method = method
+    this.input = input
+  }
+}`
+
+var connection = `/**
+ * Notification is a notification sent by the server over a connection.
+ */
+
+export interface Notification {
+  event: string;
+  data?: any;
+}
+
+/**
+ * Open a WebSocket connection to the API, resolving when it is open. Browsers
+ * cannot set headers, so the auth token is passed as the access_token parameter.
+ */
+
+function openWebSocket(url: string, authToken?: string): Promise<WebSocket> {
+  let wsURL = url.replace(/^http/, 'ws') + '/_websocket'
+  if (authToken != null) {
+    wsURL += '?access_token=' + encodeURIComponent(authToken)
+  }
+
+  return new Promise((resolve, reject) => {
+    const ws = new WebSocket(wsURL)
+    ws.onopen = () => resolve(ws)
+    ws.onerror = () => reject(new Error('WebSocket connection failed'))
+  })
 }`
 
 // Generate writes the TS client implementations to w.
@@ -72,6 +258,27 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 
 	out(w, require, fetchLibrary)
 	out(w, "\n%s\n", call)
+	out(w, "\n%s\n", batchCall)
+	out(w, "\n%s\n", connection)
+
+	for _, m := range s.Methods {
+		if m.Stream {
+			out(w, "\n%s\n", stream)
+			break
+		}
+	}
+
+	// error type guards
+	for _, e := range catalog.Errors {
+		name := "is" + e.Name
+		out(w, "\n/**\n")
+		out(w, " * %s returns true if err is %s.\n", name, e.Description)
+		out(w, " */\n\n")
+		out(w, "export function %s(err: any): err is ClientError {\n", name)
+		out(w, "  return err instanceof ClientError && err.type == '%s'\n", e.Type)
+		out(w, "}\n")
+	}
+
 	out(w, "\n\n")
 	out(w, `const reISO8601 = /(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d:[0-5]\d\.\d+([+-][0-2]\d:[0-5]\d|Z))|(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d:[0-5]\d([+-][0-2]\d:[0-5]\d|Z))|(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d([+-][0-2]\d:[0-5]\d|Z))/`)
 	out(w, "\n\n")
@@ -83,14 +290,32 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 	out(w, "\n")
 	out(w, "  private url: string\n")
 	out(w, "  private authToken?: string\n")
+	out(w, "  private codec?: Codec\n")
+	out(w, "  private compressionThreshold?: number\n")
+	out(w, "  private trace?: Record<string, string>\n")
 	out(w, "\n")
 	out(w, "  /**\n")
 	out(w, "   * Initialize.\n")
 	out(w, "   */\n")
 	out(w, "\n")
-	out(w, "  constructor(params: { url: string, authToken?: string }) {\n")
+	out(w, "  constructor(params: { url: string, authToken?: string, codec?: Codec, compressionThreshold?: number }) {\n")
 	out(w, "    this.url = params.url\n")
 	out(w, "    this.authToken = params.authToken\n")
+	out(w, "    this.codec = params.codec\n")
+	out(w, "    this.compressionThreshold = params.compressionThreshold\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Return a copy of the client forwarding the W3C traceparent and tracestate header values.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  withTrace(traceparent: string, tracestate?: string): Client {\n")
+	out(w, "    const client: Client = Object.assign(Object.create(Client.prototype), this)\n")
+	out(w, "    client.trace = { traceparent }\n")
+	out(w, "    if (tracestate != null) {\n")
+	out(w, "      client.trace.tracestate = tracestate\n")
+	out(w, "    }\n")
+	out(w, "    return client\n")
 	out(w, "  }\n")
 	out(w, "\n")
 	out(w, "  /**\n")
@@ -103,6 +328,33 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 	out(w, "      : value\n")
 	out(w, "  }\n")
 	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Decode a response body using the codec, defaulting to JSON.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  private decode(body: any): any {\n")
+	out(w, "    return this.codec\n")
+	out(w, "      ? this.codec.decode(body)\n")
+	out(w, "      : JSON.parse(body, this.decoder)\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Return a new batch of calls, which requires the server to enable batching.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  batch(): Batch {\n")
+	out(w, "    return new Batch(this.url, this.authToken, this.compressionThreshold, this.trace, this.decoder)\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Open a WebSocket connection for calls and notifications, which requires the server to enable WebSockets.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  async connect(onNotification?: (n: Notification) => void): Promise<Connection> {\n")
+	out(w, "    const ws = await openWebSocket(this.url, this.authToken)\n")
+	out(w, "    return new Connection(ws, onNotification, this.decoder)\n")
+	out(w, "  }\n")
+	out(w, "\n")
 
 	// methods
 	for _, m := range s.Methods {
@@ -111,6 +363,21 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 		out(w, "   * %s: %s\n", name, m.Description)
 		out(w, "   */\n\n")
 
+		// stream
+		if m.Stream {
+			params := "undefined"
+			if len(m.Inputs) > 0 {
+				params = "params"
+				out(w, "  async *%s(params: %sInput): AsyncGenerator<%sOutput> {\n", name, format.GoName(m.Name), format.GoName(m.Name))
+			} else {
+				out(w, "  async *%s(): AsyncGenerator<%sOutput> {\n", name, format.GoName(m.Name))
+			}
+			out(w, "    const res = await send(this.url, '%s', this.authToken, %s, this.codec, this.compressionThreshold, this.trace, 'application/x-ndjson')\n", m.Name, params)
+			out(w, "    yield* readStream(res, this.decoder)\n")
+			out(w, "  }\n\n")
+			continue
+		}
+
 		// input
 		if len(m.Inputs) > 0 {
 			out(w, "  async %s(params: %sInput)", name, format.GoName(m.Name))
@@ -130,18 +397,18 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 			out(w, "    let res = ")
 			// call
 			if len(m.Inputs) > 0 {
-				out(w, "await call(this.url, '%s', this.authToken, params)\n", m.Name)
+				out(w, "await call(this.url, '%s', this.authToken, params, this.codec, this.compressionThreshold, this.trace)\n", m.Name)
 			} else {
-				out(w, "await call(this.url, '%s', this.authToken)\n", m.Name)
+				out(w, "await call(this.url, '%s', this.authToken, undefined, this.codec, this.compressionThreshold, this.trace)\n", m.Name)
 			}
-			out(w, "    let out: %sOutput = JSON.parse(res, this.decoder)\n", format.GoName(m.Name))
+			out(w, "    let out: %sOutput = this.decode(res)\n", format.GoName(m.Name))
 			out(w, "    return out\n")
 		} else {
 			// call
 			if len(m.Inputs) > 0 {
-				out(w, "    await call(this.url, '%s', this.authToken, params)\n", m.Name)
+				out(w, "    await call(this.url, '%s', this.authToken, params, this.codec, this.compressionThreshold, this.trace)\n", m.Name)
 			} else {
-				out(w, "    await call(this.url, '%s', this.authToken)\n", m.Name)
+				out(w, "    await call(this.url, '%s', this.authToken, undefined, this.codec, this.compressionThreshold, this.trace)\n", m.Name)
 			}
 		}
 
@@ -150,5 +417,200 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 
 	out(w, "}\n")
 
+	// batch
+	out(w, "\n/**\n")
+	out(w, " * Batch is a batch of calls sent in a single request with send().\n")
+	out(w, " */\n")
+	out(w, "\n")
+	out(w, "export class Batch {\n")
+	out(w, "\n")
+	out(w, "  private calls: BatchCall<any>[] = []\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Initialize.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  constructor(private url: string, private authToken?: string, private compressionThreshold?: number, private trace?: Record<string, string>, private decoder?: (key: any, value: any) => any) {}\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Add a call to the batch.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  private add<T>(method: string, input?: any): BatchCall<T> {\n")
+	out(w, "    const c = new BatchCall<T>(method, input)\n")
+	out(w, "    this.calls.push(c)\n")
+	out(w, "    return c\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Send the calls of the batch in a single request, setting their outputs and errors.\n")
+	out(w, "   * The promise is rejected with the error of the batch request itself.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  async send(): Promise<void> {\n")
+	out(w, "    if (this.calls.length == 0) {\n")
+	out(w, "      return\n")
+	out(w, "    }\n")
+	out(w, "\n")
+	out(w, "    const calls = this.calls.map(({ method, input }) => ({ method, input }))\n")
+	out(w, "    const res = await call(this.url, '_batch', this.authToken, calls, undefined, this.compressionThreshold, this.trace)\n")
+	out(w, "    const results: any[] = JSON.parse(res, this.decoder)\n")
+	out(w, "\n")
+	out(w, "    results.forEach((result, i) => {\n")
+	out(w, "      const c = this.calls[i]\n")
+	out(w, "      if (result.error != null) {\n")
+	out(w, "        c.error = resultError(result)\n")
+	out(w, "      } else {\n")
+	out(w, "        c.output = result.result\n")
+	out(w, "      }\n")
+	out(w, "    })\n")
+	out(w, "  }\n")
+	out(w, "\n")
+
+	for _, m := range s.Methods {
+		// streams cannot be batched
+		if m.Stream {
+			continue
+		}
+
+		name := format.JsName(m.Name)
+		out(w, "  /**\n")
+		out(w, "   * %s: add a call of %s to the batch.\n", name, m.Name)
+		out(w, "   */\n\n")
+
+		// output
+		output := "void"
+		if len(m.Outputs) > 0 {
+			output = format.GoName(m.Name) + "Output"
+		}
+
+		// input
+		if len(m.Inputs) > 0 {
+			out(w, "  %s(params: %sInput): BatchCall<%s> {\n", name, format.GoName(m.Name), output)
+			out(w, "    return this.add('%s', params)\n", m.Name)
+		} else {
+			out(w, "  %s(): BatchCall<%s> {\n", name, output)
+			out(w, "    return this.add('%s')\n", m.Name)
+		}
+
+		out(w, "  }\n\n")
+	}
+
+	out(w, "}\n")
+
+	// connection
+	out(w, "\n/**\n")
+	out(w, " * Connection is a WebSocket connection multiplexing calls, see Client.connect().\n")
+	out(w, " */\n")
+	out(w, "\n")
+	out(w, "export class Connection {\n")
+	out(w, "\n")
+	out(w, "  private id = 0\n")
+	out(w, "  private pending = new Map<number, { resolve: (value: any) => void, reject: (err: Error) => void }>()\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Initialize.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  constructor(private ws: WebSocket, private onNotification?: (n: Notification) => void, private decoder?: (key: any, value: any) => any) {\n")
+	out(w, "    ws.onmessage = (e) => this.receive(e.data)\n")
+	out(w, "    ws.onclose = () => this.fail(new Error('connection closed'))\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Close the connection, rejecting pending calls.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  close() {\n")
+	out(w, "    this.ws.close()\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Receive a result or notification message.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  private receive(data: string) {\n")
+	out(w, "    const msg = JSON.parse(data, this.decoder)\n")
+	out(w, "\n")
+	out(w, "    if (msg.event != null) {\n")
+	out(w, "      if (this.onNotification) {\n")
+	out(w, "        this.onNotification({ event: msg.event, data: msg.data })\n")
+	out(w, "      }\n")
+	out(w, "      return\n")
+	out(w, "    }\n")
+	out(w, "\n")
+	out(w, "    const p = this.pending.get(msg.id)\n")
+	out(w, "    if (p == null) {\n")
+	out(w, "      return\n")
+	out(w, "    }\n")
+	out(w, "\n")
+	out(w, "    this.pending.delete(msg.id)\n")
+	out(w, "    if (msg.error != null) {\n")
+	out(w, "      p.reject(resultError(msg))\n")
+	out(w, "    } else {\n")
+	out(w, "      p.resolve(msg.result)\n")
+	out(w, "    }\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Reject pending calls with err.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  private fail(err: Error) {\n")
+	out(w, "    this.pending.forEach(p => p.reject(err))\n")
+	out(w, "    this.pending.clear()\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Call method with input, resolving with its result.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  private call(method: string, input?: any): Promise<any> {\n")
+	out(w, "    if (this.ws.readyState != WebSocket.OPEN) {\n")
+	out(w, "      return Promise.reject(new Error('connection closed'))\n")
+	out(w, "    }\n")
+	out(w, "\n")
+	out(w, "    const id = ++this.id\n")
+	out(w, "    return new Promise((resolve, reject) => {\n")
+	out(w, "      this.pending.set(id, { resolve, reject })\n")
+	out(w, "      this.ws.send(JSON.stringify({ id, method, input }))\n")
+	out(w, "    })\n")
+	out(w, "  }\n")
+	out(w, "\n")
+
+	for _, m := range s.Methods {
+		// streams are not supported
+		if m.Stream {
+			continue
+		}
+
+		name := format.JsName(m.Name)
+		out(w, "  /**\n")
+		out(w, "   * %s: %s\n", name, m.Description)
+		out(w, "   */\n\n")
+
+		// input
+		params := ""
+		if len(m.Inputs) > 0 {
+			params = ", params"
+			out(w, "  async %s(params: %sInput)", name, format.GoName(m.Name))
+		} else {
+			out(w, "  async %s()", name)
+		}
+
+		// output
+		if len(m.Outputs) > 0 {
+			out(w, ": Promise<%sOutput> {\n", format.GoName(m.Name))
+			out(w, "    return this.call('%s'%s)\n", m.Name, params)
+		} else {
+			out(w, " {\n")
+			out(w, "    await this.call('%s'%s)\n", m.Name, params)
+		}
+
+		out(w, "  }\n\n")
+	}
+
+	out(w, "}\n")
+
 	return nil
 }
@@ -2,6 +2,7 @@ package tsclient_test
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/tj/assert"
@@ -19,5 +20,94 @@ func TestGenerate(t *testing.T) {
 	err = tsclient.Generate(&act, schema, "node-fetch")
 	assert.NoError(t, err, "generating")
 
+	assertBalanced(t, act.String())
 	fixture.Assert(t, "todo_client.ts", act.Bytes())
 }
+
+func TestGenerate_stream(t *testing.T) {
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	s.Methods[1].Stream = true
+	s.Methods[2].Stream = true
+
+	var act bytes.Buffer
+	err = tsclient.Generate(&act, s, "node-fetch")
+	assert.NoError(t, err, "generating")
+
+	assertBalanced(t, act.String())
+	fixture.Assert(t, "todo_client_stream.ts", act.Bytes())
+}
+
+// assertBalanced asserts that the brackets of TypeScript source s are balanced,
+// ignoring those in comments and string literals other than the expressions
+// of template literals.
+func assertBalanced(t testing.TB, s string) {
+	t.Helper()
+
+	pairs := map[byte]byte{')': '(', ']': '[', '}': '{'}
+	var stack []byte
+	line := 1
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+		case strings.HasPrefix(s[i:], "//"):
+			for i < len(s)-1 && s[i+1] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			assert.NotEqual(t, -1, end, "unterminated comment on line %d", line)
+			line += strings.Count(s[i:i+2+end], "\n")
+			i += end + 3
+		case c == '\'' || c == '"':
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case c == '`':
+			// template literals are pushed, to be resumed when their expressions end
+			stack = append(stack, '`')
+			i = skipTemplate(s, i+1, &stack)
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) > 0 && stack[len(stack)-1] == '$' && c == '}' {
+				// end of a template literal expression
+				stack = stack[:len(stack)-1]
+				i = skipTemplate(s, i+1, &stack)
+				continue
+			}
+
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[c] {
+				t.Fatalf("unbalanced %q on line %d", c, line)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	assert.Empty(t, string(stack), "unclosed brackets")
+}
+
+// skipTemplate skips the template literal in s from i, returning the index
+// of its closing backtick, popped from stack, or of the "{" starting an
+// expression, pushed to stack as "$".
+func skipTemplate(s string, i int, stack *[]byte) int {
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '`':
+			*stack = (*stack)[:len(*stack)-1]
+			return i
+		case strings.HasPrefix(s[i:], "${"):
+			*stack = append(*stack, '$')
+			return i + 1
+		}
+	}
+	return i
+}
@@ -1,13 +1,36 @@
 module github.com/apex/rpc
 
-go 1.13
+go 1.22.0
 
 require (
-	github.com/gookit/color v1.2.6 // indirect
+	github.com/apex/log v1.9.0
+	github.com/fxamacker/cbor/v2 v2.7.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/iancoleman/strcase v0.0.0-20191112232945-16388991a334
-	github.com/json-iterator/go v1.1.9
-	github.com/sergi/go-diff v1.1.0 // indirect
-	github.com/tj/assert v0.0.0-20190920132354-ee03d75cd160
+	github.com/json-iterator/go v1.1.12
+	github.com/klauspost/compress v1.17.11
+	github.com/tj/assert v0.0.3
 	github.com/tj/go-fixture v1.0.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/tools v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gookit/color v1.2.6 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sergi/go-diff v1.1.0 // indirect
+	github.com/shibukawa/cdiff v0.1.3 // indirect
+	github.com/stretchr/testify v1.6.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
 )
@@ -1,13 +1,13 @@
 package rpc
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 )
 
-// TODO: remove health checking, this can be provided
-// in middleware and has nothing to do with RPC :D
-
 // HealthChecker is the interface used for servers providing a health check.
 type HealthChecker interface {
 	Health() error
@@ -27,3 +27,227 @@ func WriteHealth(w http.ResponseWriter, s interface{}) {
 
 	fmt.Fprintln(w, "OK")
 }
+
+// DefaultHealthTimeout is the default timeout of health checks.
+const DefaultHealthTimeout = 5 * time.Second
+
+// Probe is a kind of health probe.
+type Probe int
+
+// Probes available.
+const (
+	// Readiness probes check the server is ready to serve requests, running all checks.
+	Readiness Probe = iota
+
+	// Liveness probes check the server is alive, running only liveness checks.
+	Liveness
+)
+
+// CheckOption is a health check option.
+type CheckOption func(*healthCheck)
+
+// CheckTimeout sets the timeout of the check, defaulting to DefaultHealthTimeout.
+func CheckTimeout(d time.Duration) CheckOption {
+	return func(c *healthCheck) {
+		c.timeout = d
+	}
+}
+
+// CheckCache caches the result of the check for d.
+func CheckCache(d time.Duration) CheckOption {
+	return func(c *healthCheck) {
+		c.ttl = d
+	}
+}
+
+// CheckLiveness includes the check in liveness probes.
+func CheckLiveness() CheckOption {
+	return func(c *healthCheck) {
+		c.liveness = true
+	}
+}
+
+// CheckResult is the result of a health check.
+type CheckResult struct {
+	// Name is the name of the check.
+	Name string `json:"name"`
+
+	// Status is "ok" or "fail".
+	Status string `json:"status"`
+
+	// Error is the error message of a failed check.
+	Error string `json:"error,omitempty"`
+
+	// LatencyMS is the latency of the check in milliseconds.
+	LatencyMS float64 `json:"latency_ms"`
+
+	// Cached is true when the result was cached.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// HealthReport is a health report.
+type HealthReport struct {
+	// Status is "ok" when all checks passed, otherwise "fail".
+	Status string `json:"status"`
+
+	// Checks are the check results, in registration order.
+	Checks []CheckResult `json:"checks"`
+}
+
+// healthCheck is a registered health check.
+type healthCheck struct {
+	name     string
+	check    func(ctx context.Context) error
+	timeout  time.Duration
+	ttl      time.Duration
+	liveness bool
+
+	mu      sync.Mutex
+	result  CheckResult
+	expires time.Time
+}
+
+// run returns the result of the check, cached when fresh.
+func (c *healthCheck) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.expires) {
+		r := c.result
+		r.Cached = true
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	r := CheckResult{
+		Name:      c.name,
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		r.Status = "fail"
+		r.Error = err.Error()
+	}
+
+	if c.ttl > 0 {
+		c.result = r
+		c.expires = time.Now().Add(c.ttl)
+	}
+
+	return r
+}
+
+// Health is a registry of named health checks.
+type Health struct {
+	mu     sync.Mutex
+	checks []*healthCheck
+}
+
+// DefaultHealth is the health registry served by generated servers.
+var DefaultHealth = NewHealth()
+
+// NewHealth returns a new health registry.
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// Register a health check named name, replacing any check of the same name.
+func (h *Health) Register(name string, check func(ctx context.Context) error, options ...CheckOption) {
+	c := &healthCheck{
+		name:    name,
+		check:   check,
+		timeout: DefaultHealthTimeout,
+	}
+
+	for _, o := range options {
+		o(c)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, v := range h.checks {
+		if v.name == name {
+			h.checks[i] = c
+			return
+		}
+	}
+
+	h.checks = append(h.checks, c)
+}
+
+// Check runs the checks of probe p concurrently, returning a report. The
+// Health() method of the server s is run as a readiness check named
+// "server" if it implements the HealthChecker interface.
+func (h *Health) Check(ctx context.Context, p Probe, s interface{}) HealthReport {
+	h.mu.Lock()
+	var checks []*healthCheck
+	for _, c := range h.checks {
+		if p == Readiness || c.liveness {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.Unlock()
+
+	if hc, ok := s.(HealthChecker); ok && p == Readiness {
+		checks = append(checks, &healthCheck{
+			name:    "server",
+			timeout: DefaultHealthTimeout,
+			check: func(context.Context) error {
+				return hc.Health()
+			},
+		})
+	}
+
+	report := HealthReport{
+		Status: "ok",
+		Checks: make([]CheckResult, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *healthCheck) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+
+	return report
+}
+
+// WriteHealthReport responds with the health report as JSON, with
+// 200 OK when all checks passed, otherwise 503 Service Unavailable.
+func WriteHealthReport(w http.ResponseWriter, report HealthReport) {
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	b, _ := json.MarshalIndent(report, "", "  ")
+	writeBody(w, status, "application/json", append(b, '\n'))
+}
@@ -1,12 +1,15 @@
 package rpc_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/tj/assert"
-	
+
 	"github.com/apex/rpc"
 )
 
@@ -43,3 +46,82 @@ func TestWriteHealth(t *testing.T) {
 		assert.Equal(t, "Health check failed\n", w.Body.String())
 	})
 }
+
+// Test health registries.
+func TestHealth(t *testing.T) {
+	h := rpc.NewHealth()
+
+	var calls int
+	h.Register("cache", func(ctx context.Context) error {
+		calls++
+		return nil
+	}, rpc.CheckCache(time.Minute), rpc.CheckLiveness())
+
+	h.Register("db", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	h.Register("queue", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, rpc.CheckTimeout(10*time.Millisecond))
+
+	t.Run("readiness", func(t *testing.T) {
+		report := h.Check(context.Background(), rpc.Readiness, healthChecker{})
+		assert.Equal(t, "fail", report.Status)
+		assert.Len(t, report.Checks, 4)
+
+		assert.Equal(t, "cache", report.Checks[0].Name)
+		assert.Equal(t, "ok", report.Checks[0].Status)
+		assert.False(t, report.Checks[0].Cached)
+
+		assert.Equal(t, "db", report.Checks[1].Name)
+		assert.Equal(t, "fail", report.Checks[1].Status)
+		assert.Equal(t, "connection refused", report.Checks[1].Error)
+
+		assert.Equal(t, "queue", report.Checks[2].Name)
+		assert.Equal(t, "fail", report.Checks[2].Status)
+		assert.Equal(t, "timed out after 10ms", report.Checks[2].Error)
+
+		assert.Equal(t, "server", report.Checks[3].Name)
+		assert.Equal(t, "ok", report.Checks[3].Status)
+	})
+
+	t.Run("liveness", func(t *testing.T) {
+		report := h.Check(context.Background(), rpc.Liveness, healthChecker{errors.New("boom")})
+		assert.Equal(t, "ok", report.Status)
+		assert.Len(t, report.Checks, 1)
+		assert.Equal(t, "cache", report.Checks[0].Name)
+		assert.True(t, report.Checks[0].Cached)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+// Test writing health reports.
+func TestWriteHealthReport(t *testing.T) {
+	t.Run("passing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteHealthReport(w, rpc.HealthReport{
+			Status: "ok",
+			Checks: []rpc.CheckResult{{Name: "db", Status: "ok", LatencyMS: 1.5}},
+		})
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, map[string]interface{}{
+			"status": "ok",
+			"checks": []interface{}{
+				map[string]interface{}{"name": "db", "status": "ok", "latency_ms": 1.5},
+			},
+		}, body)
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteHealthReport(w, rpc.HealthReport{Status: "fail"})
+		assert.Equal(t, 503, w.Code)
+	})
+}
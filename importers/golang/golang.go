@@ -0,0 +1,431 @@
+// Package golang provides schema importing from existing Go source code.
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/apex/rpc/schema"
+)
+
+// Config is the import configuration.
+type Config struct {
+	// Dir is the directory the package pattern is resolved in.
+	Dir string
+
+	// Pattern is the package pattern to inspect, such as "./server".
+	Pattern string
+
+	// Server is the name of the type providing the methods.
+	Server string
+
+	// Name is the name of the API, defaulting to the package name.
+	Name string
+
+	// Version is the version of the API.
+	Version string
+}
+
+// Import returns a schema derived from the methods of the configured server type.
+//
+// Methods are expected to have the signature Method(ctx, XInput) (*XOutput, error),
+// where the input and output are optional structs. Exported methods which do not
+// match, including those with non-struct inputs or outputs, are ignored, and struct
+// types referenced by the inputs and outputs become types.
+func Import(c Config) (*schema.Schema, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:  c.Dir,
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+	}, c.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("loading packages: %q contains errors", c.Pattern)
+	}
+
+	// find the server type
+	var obj types.Object
+	var pkg *packages.Package
+	for _, p := range pkgs {
+		if o := p.Types.Scope().Lookup(c.Server); o != nil {
+			obj = o
+			pkg = p
+			break
+		}
+	}
+
+	if obj == nil {
+		return nil, fmt.Errorf("type %q not found in %q", c.Server, c.Pattern)
+	}
+
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil, fmt.Errorf("%q is not a type", c.Server)
+	}
+
+	i := &importer{
+		docs: docs(pkgs),
+		schema: &schema.Schema{
+			Name:    c.Name,
+			Version: c.Version,
+			Types:   map[string]schema.Type{},
+		},
+	}
+
+	if i.schema.Name == "" {
+		i.schema.Name = pkg.Name
+	}
+
+	if i.schema.Version == "" {
+		i.schema.Version = "1.0.0"
+	}
+
+	// methods
+	methods := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for j := 0; j < methods.Len(); j++ {
+		fn, ok := methods.At(j).Obj().(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+
+		err := i.method(fn)
+		if err != nil {
+			return nil, fmt.Errorf("method %s: %w", fn.Name(), err)
+		}
+	}
+
+	if len(i.schema.Methods) == 0 {
+		return nil, fmt.Errorf("type %q has no RPC methods", c.Server)
+	}
+
+	sort.Slice(i.schema.Methods, func(a, b int) bool {
+		return i.schema.Methods[a].Name < i.schema.Methods[b].Name
+	})
+
+	return i.schema, nil
+}
+
+// importer converts Go types to schema types.
+type importer struct {
+	docs   map[token.Pos]string
+	schema *schema.Schema
+}
+
+// method adds fn to the schema if it is an RPC method, ignoring it otherwise.
+func (i *importer) method(fn *types.Func) error {
+	sig := fn.Type().(*types.Signature)
+	params := sig.Params()
+	results := sig.Results()
+
+	// ctx, and optional input
+	if params.Len() < 1 || params.Len() > 2 || !isContext(params.At(0).Type()) {
+		return nil
+	}
+
+	// optional output, and error
+	if results.Len() < 1 || results.Len() > 2 || !isError(results.At(results.Len()-1).Type()) {
+		return nil
+	}
+
+	// struct input and output
+	var in, out *types.Struct
+	if params.Len() == 2 {
+		s, ok := structType(params.At(1).Type())
+		if !ok {
+			return nil
+		}
+		in = s
+	}
+
+	if results.Len() == 2 {
+		s, ok := structType(results.At(0).Type())
+		if !ok {
+			return nil
+		}
+		out = s
+	}
+
+	m := schema.Method{
+		Name:        strcase.ToSnake(fn.Name()),
+		Description: description(fn.Name(), i.docs[fn.Pos()]),
+	}
+
+	// inputs
+	if in != nil {
+		fields, err := i.fields(in)
+		if err != nil {
+			return fmt.Errorf("input: %w", err)
+		}
+		m.Inputs = fields
+	}
+
+	// outputs
+	if out != nil {
+		fields, err := i.fields(out)
+		if err != nil {
+			return fmt.Errorf("output: %w", err)
+		}
+		m.Outputs = fields
+	}
+
+	i.schema.Methods = append(i.schema.Methods, m)
+	return nil
+}
+
+// fields returns the schema fields of struct s.
+func (i *importer) fields(s *types.Struct) ([]schema.Field, error) {
+	fields := []schema.Field{}
+
+	for j := 0; j < s.NumFields(); j++ {
+		v := s.Field(j)
+		name, omit := jsonName(s.Tag(j))
+		if omit {
+			continue
+		}
+
+		// flatten embedded structs as encoding/json does
+		if v.Anonymous() && name == "" {
+			if e, ok := structType(v.Type()); ok {
+				embedded, err := i.fields(e)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, embedded...)
+				continue
+			}
+		}
+
+		if !v.Exported() {
+			continue
+		}
+
+		if name == "" {
+			name = v.Name()
+		}
+
+		f := schema.Field{
+			Name:        name,
+			Description: strings.TrimPrefix(description(v.Name(), i.docs[v.Pos()]), "is "),
+		}
+
+		err := i.fieldType(&f, v.Type())
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", v.Name(), err)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// fieldType assigns the schema type of t to field f.
+func (i *importer) fieldType(f *schema.Field, t types.Type) error {
+	t = deref(t)
+
+	// slices
+	if s, ok := t.Underlying().(*types.Slice); ok {
+		if b, ok := s.Elem().(*types.Basic); ok && b.Kind() == types.Byte {
+			f.Type.Type = schema.String
+			return nil
+		}
+
+		var item schema.Field
+		err := i.fieldType(&item, s.Elem())
+		if err != nil {
+			return err
+		}
+
+		if item.Type.Type == schema.Array {
+			return fmt.Errorf("nested arrays are not supported")
+		}
+
+		f.Type.Type = schema.Array
+		f.Items = schema.ItemsObject(item.Type)
+		return nil
+	}
+
+	kind, err := i.kind(t)
+	if err != nil {
+		return err
+	}
+
+	f.Type = kind
+	return nil
+}
+
+// kind returns the schema type of t.
+func (i *importer) kind(t types.Type) (schema.TypeObject, error) {
+	t = deref(t)
+
+	if isTime(t) {
+		return schema.TypeObject{Type: schema.Timestamp}, nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return schema.TypeObject{Type: schema.String}, nil
+		case u.Info()&types.IsBoolean != 0:
+			return schema.TypeObject{Type: schema.Bool}, nil
+		case u.Info()&types.IsInteger != 0:
+			return schema.TypeObject{Type: schema.Int}, nil
+		case u.Info()&types.IsFloat != 0:
+			return schema.TypeObject{Type: schema.Float}, nil
+		}
+	case *types.Map, *types.Interface:
+		return schema.TypeObject{Type: schema.Object}, nil
+	case *types.Slice:
+		return schema.TypeObject{Type: schema.Array}, nil
+	case *types.Struct:
+		named, ok := t.(*types.Named)
+		if !ok {
+			return schema.TypeObject{}, fmt.Errorf("anonymous structs are not supported")
+		}
+
+		name, err := i.namedType(named, u)
+		if err != nil {
+			return schema.TypeObject{}, err
+		}
+
+		return schema.TypeObject{Ref: schema.Ref{Value: "#/types/" + name}}, nil
+	}
+
+	return schema.TypeObject{}, fmt.Errorf("unsupported type %s", t)
+}
+
+// namedType adds the named struct t to the schema types, returning its name.
+func (i *importer) namedType(t *types.Named, s *types.Struct) (string, error) {
+	obj := t.Obj()
+	name := strcase.ToSnake(obj.Name())
+
+	if _, ok := i.schema.Types[name]; ok {
+		return name, nil
+	}
+
+	// reserve the name to support recursive types
+	i.schema.Types[name] = schema.Type{}
+
+	fields, err := i.fields(s)
+	if err != nil {
+		return "", fmt.Errorf("type %s: %w", obj.Name(), err)
+	}
+
+	i.schema.Types[name] = schema.Type{
+		Description: description(obj.Name(), i.docs[obj.Pos()]),
+		Properties:  fields,
+	}
+
+	return name, nil
+}
+
+// docs returns the doc comments of declarations keyed by their position.
+func docs(pkgs []*packages.Package) map[token.Pos]string {
+	m := map[token.Pos]string{}
+
+	for _, p := range pkgs {
+		for _, file := range p.Syntax {
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncDecl:
+					m[n.Name.Pos()] = n.Doc.Text()
+				case *ast.GenDecl:
+					for _, spec := range n.Specs {
+						s, ok := spec.(*ast.TypeSpec)
+						if !ok {
+							continue
+						}
+
+						doc := s.Doc
+						if doc == nil && len(n.Specs) == 1 {
+							doc = n.Doc
+						}
+						m[s.Name.Pos()] = doc.Text()
+					}
+				case *ast.Field:
+					doc := n.Doc
+					if doc == nil {
+						doc = n.Comment
+					}
+					for _, name := range n.Names {
+						m[name.Pos()] = doc.Text()
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	return m
+}
+
+// description returns a description from doc comment, with the
+// leading name removed to match the schema's sentence style.
+func description(name, doc string) string {
+	s := strings.Join(strings.Fields(doc), " ")
+	s = strings.TrimPrefix(s, name+" ")
+	return s
+}
+
+// jsonName returns the name from a field's json tag, and
+// whether or not the field is omitted entirely.
+func jsonName(tag string) (string, bool) {
+	s, ok := reflect.StructTag(tag).Lookup("json")
+	if !ok {
+		return "", false
+	}
+
+	if s == "-" {
+		return "", true
+	}
+
+	return strings.Split(s, ",")[0], false
+}
+
+// structType returns the struct underlying t.
+func structType(t types.Type) (*types.Struct, bool) {
+	s, ok := deref(t).Underlying().(*types.Struct)
+	return s, ok
+}
+
+// deref returns the element type of pointers.
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+// isContext returns true if t is context.Context.
+func isContext(t types.Type) bool {
+	return isNamed(t, "context", "Context")
+}
+
+// isTime returns true if t is time.Time.
+func isTime(t types.Type) bool {
+	return isNamed(t, "time", "Time")
+}
+
+// isError returns true if t is the error interface.
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// isNamed returns true if t is the named type pkg.name.
+func isNamed(t types.Type, pkg, name string) bool {
+	n, ok := t.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	return n.Obj().Pkg().Path() == pkg && n.Obj().Name() == name
+}
@@ -0,0 +1,32 @@
+package golang_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tj/assert"
+	"github.com/tj/go-fixture"
+
+	"github.com/apex/rpc/importers/golang"
+)
+
+func TestImport(t *testing.T) {
+	s, err := golang.Import(golang.Config{
+		Pattern: "./testdata/todo",
+		Server:  "Server",
+	})
+	assert.NoError(t, err, "importing")
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	assert.NoError(t, err, "marshaling")
+
+	fixture.Assert(t, "todo_schema.json", b)
+}
+
+func TestImport_missing(t *testing.T) {
+	_, err := golang.Import(golang.Config{
+		Pattern: "./testdata/todo",
+		Server:  "Missing",
+	})
+	assert.EqualError(t, err, `type "Missing" not found in "./testdata/todo"`)
+}
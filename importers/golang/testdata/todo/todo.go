@@ -0,0 +1,82 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// Item is a to-do item.
+type Item struct {
+	// ID is the id of the item.
+	ID int `json:"id"`
+
+	// Text is the to-do item text.
+	Text string `json:"text"`
+
+	// CreatedAt is the time the to-do item was created.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddItemInput params.
+type AddItemInput struct {
+	// Item is the item to add.
+	Item string `json:"item"`
+}
+
+// GetItemsOutput params.
+type GetItemsOutput struct {
+	// Items is the list of to-do items.
+	Items []Item `json:"items"`
+}
+
+// RemoveItemInput params.
+type RemoveItemInput struct {
+	// ID is the id of the item to remove.
+	ID int `json:"id"`
+}
+
+// RemoveItemOutput params.
+type RemoveItemOutput struct {
+	// Item is the item removed.
+	Item *Item `json:"item"`
+}
+
+// Server is the to-do list server.
+type Server struct {
+	items []Item
+}
+
+// AddItem adds an item to the list.
+func (s *Server) AddItem(ctx context.Context, in AddItemInput) error {
+	return nil
+}
+
+// GetItems returns all items in the list.
+func (s *Server) GetItems(ctx context.Context) (*GetItemsOutput, error) {
+	return nil, nil
+}
+
+// RemoveItem removes an item from the to-do list.
+func (s *Server) RemoveItem(ctx context.Context, in RemoveItemInput) (*RemoveItemOutput, error) {
+	return nil, nil
+}
+
+// Health is not an RPC method.
+func (s *Server) Health() error {
+	return nil
+}
+
+// Lookup has a non-struct input and is not an RPC method.
+func (s *Server) Lookup(ctx context.Context, id string) (*GetItemsOutput, error) {
+	return nil, nil
+}
+
+// Count has a non-struct output and is not an RPC method.
+func (s *Server) Count(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// reset is unexported and ignored.
+func (s *Server) reset(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,649 @@
+// Package openapi provides schema importing from OpenAPI 3 documents.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"gopkg.in/yaml.v3"
+
+	"github.com/apex/rpc/schema"
+)
+
+// document model.
+type document struct {
+	OpenAPI    string                                `json:"openapi"`
+	Info       info                                  `json:"info"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components components                            `json:"components"`
+}
+
+// info model.
+type info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// components model.
+type components struct {
+	Schemas       map[string]*schemaObject `json:"schemas"`
+	RequestBodies map[string]*body         `json:"requestBodies"`
+	Responses     map[string]*body         `json:"responses"`
+}
+
+// operation model.
+type operation struct {
+	OperationID string           `json:"operationId"`
+	Summary     string           `json:"summary"`
+	Description string           `json:"description"`
+	Parameters  []interface{}    `json:"parameters"`
+	RequestBody *body            `json:"requestBody"`
+	Responses   map[string]*body `json:"responses"`
+}
+
+// body model, used for both request bodies and responses.
+type body struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*mediaType `json:"content"`
+}
+
+// mediaType model.
+type mediaType struct {
+	Schema *schemaObject `json:"schema"`
+}
+
+// schemaObject model.
+type schemaObject struct {
+	Ref                  string                   `json:"$ref"`
+	Type                 interface{}              `json:"type"`
+	Format               string                   `json:"format"`
+	Description          string                   `json:"description"`
+	Properties           map[string]*schemaObject `json:"properties"`
+	Required             []string                 `json:"required"`
+	Items                *schemaObject            `json:"items"`
+	Enum                 []interface{}            `json:"enum"`
+	Default              interface{}              `json:"default"`
+	ReadOnly             bool                     `json:"readOnly"`
+	AllOf                []*schemaObject          `json:"allOf"`
+	OneOf                []*schemaObject          `json:"oneOf"`
+	AnyOf                []*schemaObject          `json:"anyOf"`
+	AdditionalProperties interface{}              `json:"additionalProperties"`
+}
+
+// Import returns a schema converted from the OpenAPI 3 JSON or YAML document b.
+//
+// Component schemas become types, and POST operations become methods. Constructs
+// which cannot be represented are reported in the returned warnings, and are either
+// skipped or loosened to the closest equivalent.
+func Import(b []byte) (*schema.Schema, []string, error) {
+	var v interface{}
+	err := yaml.Unmarshal(b, &v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	// YAML is a superset of JSON, so normalize to JSON
+	b, err = json.Marshal(v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	var doc document
+	err = json.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, nil, fmt.Errorf("unsupported OpenAPI version %q, must be 3.x", doc.OpenAPI)
+	}
+
+	i := &importer{
+		doc: &doc,
+		schema: &schema.Schema{
+			Name:        strcase.ToSnake(doc.Info.Title),
+			Version:     doc.Info.Version,
+			Description: doc.Info.Description,
+			Methods:     []schema.Method{},
+			Types:       map[string]schema.Type{},
+		},
+	}
+
+	// types
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		s := doc.Components.Schemas[name]
+		if s == nil {
+			i.warnf("components.schemas.%s: null schemas are ignored", name)
+			continue
+		}
+
+		if !isObject(s) {
+			i.warnf("components.schemas.%s: only object schemas can be types, references are inlined", name)
+			continue
+		}
+		i.typ(typeName(name), s, "components.schemas."+name)
+	}
+
+	// methods
+	for _, path := range sortedKeys(doc.Paths) {
+		if _, ok := doc.Paths[path]["parameters"]; ok {
+			i.warnf("%s: path parameters are not supported, use the request body", path)
+		}
+
+		for _, verb := range sortedKeys(doc.Paths[path]) {
+			if !isVerb(verb) {
+				continue
+			}
+
+			if verb != "post" {
+				i.warnf("%s %s: only POST operations are supported", strings.ToUpper(verb), path)
+				continue
+			}
+
+			var op operation
+			err := json.Unmarshal(doc.Paths[path][verb], &op)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing POST %s: %w", path, err)
+			}
+
+			err = i.method(path, &op)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	sort.Strings(i.warnings)
+	return i.schema, i.warnings, nil
+}
+
+// importer converts OpenAPI documents to schemas.
+type importer struct {
+	doc      *document
+	schema   *schema.Schema
+	warnings []string
+}
+
+// warnf adds a warning.
+func (i *importer) warnf(format string, args ...interface{}) {
+	i.warnings = append(i.warnings, fmt.Sprintf(format, args...))
+}
+
+// method adds the operation at path to the schema.
+func (i *importer) method(path string, op *operation) error {
+	at := "POST " + path
+
+	name := op.OperationID
+	if name == "" {
+		name = strings.Trim(path, "/")
+	}
+
+	m := schema.Method{
+		Name:        strcase.ToSnake(name),
+		Description: op.Summary,
+	}
+
+	if m.Description == "" {
+		m.Description = op.Description
+	}
+
+	if len(op.Parameters) > 0 {
+		i.warnf("%s: parameters are not supported, use the request body", at)
+	}
+
+	// inputs
+	if op.RequestBody != nil {
+		s, err := i.bodySchema(op.RequestBody, i.doc.Components.RequestBodies, at+" request body")
+		if err != nil {
+			return err
+		}
+
+		if s != nil {
+			m.Inputs = i.objectFields(s, m.Name+"_input", at+" request body")
+		}
+	}
+
+	// outputs
+	for _, code := range sortedKeys(op.Responses) {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+
+		s, err := i.bodySchema(op.Responses[code], i.doc.Components.Responses, at+" response "+code)
+		if err != nil {
+			return err
+		}
+
+		if s != nil {
+			m.Outputs = i.objectFields(s, m.Name+"_output", at+" response "+code)
+		}
+		break
+	}
+
+	i.schema.Methods = append(i.schema.Methods, m)
+	return nil
+}
+
+// bodySchema returns the JSON schema of a request body or response.
+func (i *importer) bodySchema(b *body, refs map[string]*body, at string) (*schemaObject, error) {
+	if b == nil {
+		return nil, fmt.Errorf("%s: must be an object", at)
+	}
+
+	if b.Ref != "" {
+		name := b.Ref[strings.LastIndex(b.Ref, "/")+1:]
+		r, ok := refs[name]
+		if !ok {
+			i.warnf("%s: unresolved reference %q", at, b.Ref)
+			return nil, nil
+		}
+
+		if r == nil {
+			return nil, fmt.Errorf("%s: reference %q must be an object", at, b.Ref)
+		}
+
+		b = r
+	}
+
+	for _, t := range sortedKeys(b.Content) {
+		if t == "application/json" || strings.HasSuffix(t, "+json") {
+			c := b.Content[t]
+			if c == nil {
+				return nil, fmt.Errorf("%s: media type %q must be an object", at, t)
+			}
+
+			if c.Schema == nil {
+				i.warnf("%s: media type %q has no schema, ignoring", at, t)
+			}
+
+			return c.Schema, nil
+		}
+	}
+
+	if len(b.Content) > 0 {
+		i.warnf("%s: only application/json content is supported", at)
+	}
+
+	return nil, nil
+}
+
+// objectFields returns the fields of object schema s.
+func (i *importer) objectFields(s *schemaObject, name, at string) []schema.Field {
+	s = i.resolve(s, at)
+	if s == nil {
+		return nil
+	}
+
+	if !isObject(s) {
+		i.warnf("%s: must be an object, ignoring %s", at, kind(s))
+		return nil
+	}
+
+	return i.fields(s, name, at)
+}
+
+// typ adds the object schema s as a type.
+func (i *importer) typ(name string, s *schemaObject, at string) {
+	if _, ok := i.schema.Types[name]; ok {
+		return
+	}
+
+	// reserve the name to support recursive types
+	i.schema.Types[name] = schema.Type{}
+
+	i.schema.Types[name] = schema.Type{
+		Description: s.Description,
+		Properties:  i.fields(s, name, at),
+	}
+}
+
+// fields returns the properties of object schema s as fields.
+func (i *importer) fields(s *schemaObject, parent, at string) []schema.Field {
+	props, required := i.merge(s, at, map[string]bool{})
+
+	fields := []schema.Field{}
+	for _, name := range sortedKeys(props) {
+		p := props[name]
+		f := i.field(name, p, parent, at+"."+name)
+		f.Required = contains(required, name)
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// merge returns the properties and required fields of s, including allOf compositions,
+// where seen holds the references being merged to break cycles.
+func (i *importer) merge(s *schemaObject, at string, seen map[string]bool) (map[string]*schemaObject, []string) {
+	props := map[string]*schemaObject{}
+	required := append([]string{}, s.Required...)
+
+	for k, v := range s.Properties {
+		props[k] = v
+	}
+
+	for _, sub := range s.AllOf {
+		ref := ""
+		if sub != nil {
+			ref = sub.Ref
+		}
+
+		if ref != "" && seen[ref] {
+			i.warnf("%s: cyclic reference %q is ignored", at, ref)
+			continue
+		}
+
+		sub = i.resolve(sub, at)
+		if sub == nil {
+			continue
+		}
+
+		seen[ref] = true
+		p, r := i.merge(sub, at, seen)
+		delete(seen, ref)
+
+		for k, v := range p {
+			props[k] = v
+		}
+		required = append(required, r...)
+	}
+
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		i.warnf("%s: oneOf and anyOf are not supported, only shared properties are imported", at)
+	}
+
+	if s.AdditionalProperties != nil && s.AdditionalProperties != false && len(props) > 0 {
+		i.warnf("%s: additionalProperties are not supported alongside properties", at)
+	}
+
+	return props, required
+}
+
+// field returns the field for property s.
+func (i *importer) field(name string, s *schemaObject, parent, at string) schema.Field {
+	if s == nil {
+		i.warnf("%s: null schemas are imported as objects", at)
+		return schema.Field{Name: name, Type: schema.TypeObject{Type: schema.Object}}
+	}
+
+	f := schema.Field{
+		Name:        name,
+		Description: s.Description,
+		ReadOnly:    s.ReadOnly,
+	}
+
+	// references to object types
+	if ref := i.typeRef(s); ref != "" {
+		f.Type.Ref.Value = ref
+		return f
+	}
+
+	s = i.resolve(s, at)
+	if s == nil {
+		f.Type.Type = schema.Object
+		return f
+	}
+
+	if f.Description == "" {
+		f.Description = s.Description
+	}
+	f.ReadOnly = f.ReadOnly || s.ReadOnly
+	f.Default = s.Default
+	f.Enum = i.enum(s, at)
+
+	switch {
+	case kind(s) == "array":
+		f.Type.Type = schema.Array
+		f.Items = schema.ItemsObject(i.itemType(s.Items, parent+"_"+name, at+"[]"))
+	case isObject(s) && i.hasProperties(s, map[string]bool{}):
+		t := typeName(parent + "_" + name)
+		i.typ(t, s, at)
+		f.Type.Ref.Value = "#/types/" + t
+	default:
+		f.Type.Type = i.primitive(s, at)
+	}
+
+	return f
+}
+
+// itemType returns the type of array items s.
+func (i *importer) itemType(s *schemaObject, name, at string) schema.TypeObject {
+	if s == nil {
+		i.warnf("%s: arrays without items are imported as arrays of objects", at)
+		return schema.TypeObject{Type: schema.Object}
+	}
+
+	if ref := i.typeRef(s); ref != "" {
+		return schema.TypeObject{Ref: schema.Ref{Value: ref}}
+	}
+
+	s = i.resolve(s, at)
+	if s == nil {
+		return schema.TypeObject{Type: schema.Object}
+	}
+
+	switch {
+	case kind(s) == "array":
+		i.warnf("%s: nested arrays are not supported, imported as arrays of objects", at)
+		return schema.TypeObject{Type: schema.Object}
+	case isObject(s) && i.hasProperties(s, map[string]bool{}):
+		t := typeName(name + "_item")
+		i.typ(t, s, at)
+		return schema.TypeObject{Ref: schema.Ref{Value: "#/types/" + t}}
+	default:
+		return schema.TypeObject{Type: i.primitive(s, at)}
+	}
+}
+
+// primitive returns the primitive kind of s.
+func (i *importer) primitive(s *schemaObject, at string) schema.Kind {
+	switch kind(s) {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return schema.Timestamp
+		case "", "email", "uri", "uuid", "hostname", "password", "byte", "binary", "date":
+			return schema.String
+		default:
+			i.warnf("%s: unknown string format %q, imported as string", at, s.Format)
+			return schema.String
+		}
+	case "integer":
+		return schema.Int
+	case "number":
+		return schema.Float
+	case "boolean":
+		return schema.Bool
+	case "object":
+		return schema.Object
+	case "":
+		if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+			i.warnf("%s: oneOf and anyOf are not supported, imported as object", at)
+		} else {
+			i.warnf("%s: schemas without a type are imported as objects", at)
+		}
+		return schema.Object
+	default:
+		i.warnf("%s: unsupported type %q, imported as object", at, kind(s))
+		return schema.Object
+	}
+}
+
+// enum returns the string enumeration of s.
+func (i *importer) enum(s *schemaObject, at string) []string {
+	if len(s.Enum) == 0 {
+		return nil
+	}
+
+	if kind(s) != "string" {
+		i.warnf("%s: only string enums are supported", at)
+		return nil
+	}
+
+	var values []string
+	for _, v := range s.Enum {
+		if v, ok := v.(string); ok {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// typeRef returns a type reference if s refers to an object component,
+// directly or as the only allOf composition, commonly used to describe a reference.
+func (i *importer) typeRef(s *schemaObject) string {
+	if s.Ref == "" && len(s.AllOf) == 1 && s.AllOf[0] != nil && len(s.Properties) == 0 {
+		return i.typeRef(s.AllOf[0])
+	}
+
+	if s.Ref == "" {
+		return ""
+	}
+
+	name := refName(s.Ref)
+	c, ok := i.doc.Components.Schemas[name]
+	if !ok || !isObject(c) {
+		return ""
+	}
+
+	return "#/types/" + typeName(name)
+}
+
+// hasProperties returns true if s has properties, including allOf compositions,
+// where seen holds the references being visited to break cycles.
+func (i *importer) hasProperties(s *schemaObject, seen map[string]bool) bool {
+	if len(s.Properties) > 0 {
+		return true
+	}
+
+	for _, sub := range s.AllOf {
+		if sub == nil {
+			continue
+		}
+
+		ref := sub.Ref
+		if ref != "" {
+			if seen[ref] {
+				continue
+			}
+			sub = i.doc.Components.Schemas[refName(ref)]
+		}
+
+		if sub == nil {
+			continue
+		}
+
+		seen[ref] = true
+		ok := i.hasProperties(sub, seen)
+		delete(seen, ref)
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve returns the schema s refers to, following references to references, or s itself.
+func (i *importer) resolve(s *schemaObject, at string) *schemaObject {
+	seen := map[string]bool{}
+
+	for s != nil && s.Ref != "" {
+		if seen[s.Ref] {
+			i.warnf("%s: cyclic reference %q is ignored", at, s.Ref)
+			return nil
+		}
+		seen[s.Ref] = true
+
+		if !strings.HasPrefix(s.Ref, "#/components/schemas/") {
+			i.warnf("%s: unsupported reference %q", at, s.Ref)
+			return nil
+		}
+
+		r, ok := i.doc.Components.Schemas[refName(s.Ref)]
+		if !ok {
+			i.warnf("%s: unresolved reference %q", at, s.Ref)
+			return nil
+		}
+
+		s = r
+	}
+
+	if s == nil {
+		i.warnf("%s: null schemas are ignored", at)
+		return nil
+	}
+
+	return s
+}
+
+// kind returns the type of s, ignoring "null" in OpenAPI 3.1 type arrays.
+func kind(s *schemaObject) string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, v := range t {
+			if v, ok := v.(string); ok && v != "null" {
+				return v
+			}
+		}
+	}
+
+	if len(s.Properties) > 0 || len(s.AllOf) > 0 {
+		return "object"
+	}
+
+	return ""
+}
+
+// isObject returns true if s is an object schema.
+func isObject(s *schemaObject) bool {
+	return s != nil && s.Ref == "" && kind(s) == "object"
+}
+
+// isVerb returns true if s is a path item operation.
+func isVerb(s string) bool {
+	switch s {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	default:
+		return false
+	}
+}
+
+// refName returns the name of a component reference.
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// typeName returns a schema type name.
+func typeName(s string) string {
+	return strcase.ToSnake(s)
+}
+
+// contains returns true if s is in values.
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the sorted keys of map m.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
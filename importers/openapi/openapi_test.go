@@ -0,0 +1,101 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/tj/assert"
+	"github.com/tj/go-fixture"
+
+	"github.com/apex/rpc/importers/openapi"
+)
+
+func TestImport(t *testing.T) {
+	b, err := os.ReadFile("testdata/pets.yaml")
+	assert.NoError(t, err, "reading")
+
+	s, warnings, err := openapi.Import(b)
+	assert.NoError(t, err, "importing")
+
+	assert.Equal(t, []string{
+		"/pets/{id}: path parameters are not supported, use the request body",
+		"GET /pets/{id}: only POST operations are supported",
+		"POST /list_pets: parameters are not supported, use the request body",
+		"components.schemas.Pet.photo: oneOf and anyOf are not supported, imported as object",
+		"components.schemas.Status: only object schemas can be types, references are inlined",
+	}, warnings)
+
+	act, err := json.MarshalIndent(s, "", "  ")
+	assert.NoError(t, err, "marshaling")
+
+	fixture.Assert(t, "pets_schema.json", act)
+}
+
+func TestImport_version(t *testing.T) {
+	_, _, err := openapi.Import([]byte(`{ "swagger": "2.0" }`))
+	assert.EqualError(t, err, `unsupported OpenAPI version "", must be 3.x`)
+}
+
+func TestImport_nullMediaType(t *testing.T) {
+	_, _, err := openapi.Import([]byte(`
+openapi: 3.0.3
+paths:
+  /add_pet:
+    post:
+      requestBody:
+        content:
+          application/json:
+`))
+	assert.EqualError(t, err, `POST /add_pet request body: media type "application/json" must be an object`)
+}
+
+func TestImport_nullSchemas(t *testing.T) {
+	s, warnings, err := openapi.Import([]byte(`
+openapi: 3.0.3
+components:
+  schemas:
+    Foo:
+    Bar:
+      type: object
+      properties:
+        a:
+`))
+	assert.NoError(t, err, "importing")
+
+	assert.Equal(t, []string{
+		"components.schemas.Bar.a: null schemas are imported as objects",
+		"components.schemas.Foo: null schemas are ignored",
+	}, warnings)
+
+	assert.Equal(t, "object", string(s.Types["bar"].Properties[0].Type.Type))
+}
+
+func TestImport_cyclicAllOf(t *testing.T) {
+	s, warnings, err := openapi.Import([]byte(`
+openapi: 3.0.3
+components:
+  schemas:
+    A:
+      allOf:
+        - $ref: '#/components/schemas/B'
+        - properties:
+            a:
+              type: string
+    B:
+      allOf:
+        - $ref: '#/components/schemas/A'
+        - properties:
+            b:
+              type: string
+`))
+	assert.NoError(t, err, "importing")
+
+	assert.Equal(t, []string{
+		`components.schemas.A: cyclic reference "#/components/schemas/B" is ignored`,
+		`components.schemas.B: cyclic reference "#/components/schemas/A" is ignored`,
+	}, warnings)
+
+	assert.Len(t, s.Types["a"].Properties, 2)
+	assert.Len(t, s.Types["b"].Properties, 2)
+}
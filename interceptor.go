@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"context"
+)
+
+// Handler is a method invocation with decoded input, which is nil
+// for methods without inputs.
+type Handler func(ctx context.Context, in interface{}) (interface{}, error)
+
+// Interceptor intercepts the invocation of method with decoded input,
+// invoking next to continue the invocation, or returning early.
+type Interceptor func(ctx context.Context, method string, in interface{}, next Handler) (interface{}, error)
+
+// MethodInterceptor is the interface used for servers intercepting method invocations.
+type MethodInterceptor interface {
+	Intercept(ctx context.Context, method string, in interface{}, next Handler) (interface{}, error)
+}
+
+// Chain returns an interceptor invoking interceptors in order, so
+// the first interceptor is the outermost.
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, method string, in interface{}, next Handler) (interface{}, error) {
+		h := next
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			h = bind(interceptors[i], method, h)
+		}
+		return h(ctx, in)
+	}
+}
+
+// bind returns a handler invoking interceptor i with next.
+func bind(i Interceptor, method string, next Handler) Handler {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		return i(ctx, method, in, next)
+	}
+}
+
+// Invoke invokes method with decoded input using h, via the Intercept()
+// method of the server s if it implements the MethodInterceptor interface.
+func Invoke(ctx context.Context, s interface{}, method string, in interface{}, h Handler) (interface{}, error) {
+	if i, ok := s.(MethodInterceptor); ok {
+		return i.Intercept(ctx, method, in, h)
+	}
+	return h(ctx, in)
+}
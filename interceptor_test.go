@@ -0,0 +1,58 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// interceptedServer is a server implementing MethodInterceptor.
+type interceptedServer struct {
+	calls []string
+}
+
+// Intercept implementation.
+func (s *interceptedServer) Intercept(ctx context.Context, method string, in interface{}, next rpc.Handler) (interface{}, error) {
+	return rpc.Chain(s.record("first"), s.record("second"))(ctx, method, in, next)
+}
+
+// record returns an interceptor recording its invocation.
+func (s *interceptedServer) record(name string) rpc.Interceptor {
+	return func(ctx context.Context, method string, in interface{}, next rpc.Handler) (interface{}, error) {
+		s.calls = append(s.calls, name+" "+method)
+		return next(ctx, in)
+	}
+}
+
+// Test method invocation.
+func TestInvoke(t *testing.T) {
+	echo := func(ctx context.Context, in interface{}) (interface{}, error) {
+		return in, nil
+	}
+
+	t.Run("without an interceptor", func(t *testing.T) {
+		res, err := rpc.Invoke(context.Background(), struct{}{}, "echo", "hello", echo)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", res)
+	})
+
+	t.Run("with chained interceptors", func(t *testing.T) {
+		s := &interceptedServer{}
+		res, err := rpc.Invoke(context.Background(), s, "echo", "hello", echo)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", res)
+		assert.Equal(t, []string{"first echo", "second echo"}, s.calls)
+	})
+
+	t.Run("with an interceptor returning early", func(t *testing.T) {
+		deny := func(ctx context.Context, method string, in interface{}, next rpc.Handler) (interface{}, error) {
+			return nil, rpc.Forbidden("Access denied")
+		}
+
+		_, err := rpc.Chain(deny)(context.Background(), "echo", "hello", echo)
+		assert.EqualError(t, err, "Access denied")
+	})
+}
@@ -0,0 +1,29 @@
+// Package catalog provides the standard error types of the rpc package,
+// used to generate client predicates.
+package catalog
+
+// Error is a standard error type.
+type Error struct {
+	// Name is the Go name of the error constructor, such as "NotFound".
+	Name string
+
+	// Type is the stable error type written in responses, such as "not_found".
+	Type string
+
+	// Description is a short description, such as "a not found error".
+	Description string
+}
+
+// Errors is the catalog of standard error types.
+var Errors = []Error{
+	{"BadRequest", "bad_request", "a bad request error"},
+	{"Invalid", "invalid", "a validation error"},
+	{"Unauthorized", "unauthorized", "an unauthorized error"},
+	{"Forbidden", "forbidden", "a forbidden error"},
+	{"NotFound", "not_found", "a not found error"},
+	{"Conflict", "conflict", "a conflict error"},
+	{"PreconditionFailed", "precondition_failed", "a precondition failed error"},
+	{"RateLimited", "rate_limited", "a rate limited error"},
+	{"Internal", "internal", "an internal server error"},
+	{"Unavailable", "unavailable", "a service unavailable error"},
+}
@@ -50,6 +50,14 @@ func GoInputType(types, method string) string {
 	return fmt.Sprintf("%s.%sInput", types, GoName(method))
 }
 
+// GoOutputType returns the name of a method output type
+func GoOutputType(types, method string) string {
+	if len(types) == 0 {
+		return fmt.Sprintf("%sOutput", GoName(method))
+	}
+	return fmt.Sprintf("%s.%sOutput", types, GoName(method))
+}
+
 // JsName returns a name formatted for JS.
 func JsName(s string) string {
 	return strcase.ToLowerCamel(s)
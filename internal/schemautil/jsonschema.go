@@ -0,0 +1,75 @@
+package schemautil
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/apex/rpc/schema"
+)
+
+// JSONSchema is a JSON Schema object, shared by the OpenAPI and JSON Schema generators.
+type JSONSchema struct {
+	Ref         string                 `json:"$ref,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+	Default     interface{}            `json:"default,omitempty"`
+	ReadOnly    bool                   `json:"readOnly,omitempty"`
+	Examples    []interface{}          `json:"examples,omitempty"`
+	OneOf       []*JSONSchema          `json:"oneOf,omitempty"`
+}
+
+// Object returns an object schema for fields, with references to types
+// prefixed by refPrefix, such as "#/$defs/".
+func Object(fields []schema.Field, refPrefix string) *JSONSchema {
+	o := &JSONSchema{
+		Type:       "object",
+		Properties: map[string]*JSONSchema{},
+	}
+
+	for _, f := range fields {
+		p := Property(f.Type.Type, f.Type.Ref, refPrefix)
+		p.Description = f.Description
+		p.Enum = f.Enum
+		p.Default = f.Default
+		p.ReadOnly = f.ReadOnly
+
+		if f.Type.Type == schema.Array {
+			p.Items = Property(f.Items.Type, f.Items.Ref, refPrefix)
+		}
+
+		if f.Required {
+			o.Required = append(o.Required, f.Name)
+		}
+
+		o.Properties[f.Name] = p
+	}
+
+	sort.Strings(o.Required)
+	return o
+}
+
+// Property returns a property schema for kind, or the referenced type
+// prefixed by refPrefix.
+func Property(kind schema.Kind, ref schema.Ref, refPrefix string) *JSONSchema {
+	if ref.Value != "" {
+		return &JSONSchema{
+			Ref: refPrefix + strings.TrimPrefix(ref.Value, "#/types/"),
+		}
+	}
+
+	switch kind {
+	case schema.Int:
+		return &JSONSchema{Type: "integer"}
+	case schema.Float:
+		return &JSONSchema{Type: "number"}
+	case schema.Timestamp:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	default:
+		return &JSONSchema{Type: string(kind)}
+	}
+}
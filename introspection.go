@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SchemaAuthorizer is the interface used for servers authorizing access to
+// the private methods and types of their schema.
+type SchemaAuthorizer interface {
+	AuthorizeSchema(r *http.Request) bool
+}
+
+// SchemaDocument is the schema of a generated server, served by WriteSchema.
+type SchemaDocument struct {
+	// Version is the schema version, used for the ETag header.
+	Version string
+
+	// Public is the JSON schema without private methods and types.
+	Public []byte
+
+	// Private is the JSON schema including private methods and types,
+	// or nil when the schema has none.
+	Private []byte
+}
+
+// WriteSchema writes the schema document doc of server s, including private
+// methods and types only when s implements the SchemaAuthorizer interface
+// and authorizes request r. The weak ETag header is derived from the version,
+// as the body may be compressed, responding with 304 Not Modified when matched
+// by the If-None-Match header.
+func WriteSchema(w http.ResponseWriter, r *http.Request, s interface{}, doc SchemaDocument) {
+	b := doc.Public
+	etag := doc.Version
+	if a, ok := s.(SchemaAuthorizer); ok && doc.Private != nil && a.AuthorizeSchema(r) {
+		b = doc.Private
+		etag += "+private"
+	}
+
+	h := w.Header()
+	h.Set("Cache-Control", "no-cache")
+	h.Add("Vary", "Authorization")
+
+	if doc.Version != "" {
+		etag = `W/"` + etag + `"`
+		h.Set("ETag", etag)
+
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	writeBody(w, http.StatusOK, "application/json", b)
+}
+
+// etagMatch returns true if the If-None-Match header value s matches etag,
+// using the weak comparison.
+func etagMatch(s, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
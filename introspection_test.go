@@ -0,0 +1,85 @@
+package rpc_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// schemaServer implementation.
+type schemaServer struct{}
+
+// AuthorizeSchema implementation.
+func (schemaServer) AuthorizeSchema(r *http.Request) bool {
+	return r.Header.Get("Authorization") == "Bearer secret"
+}
+
+// schemaDocument is a test schema document.
+var schemaDocument = rpc.SchemaDocument{
+	Version: "1.0.0",
+	Public:  []byte(`{"methods":[]}`),
+	Private: []byte(`{"methods":[{"name":"reset"}]}`),
+}
+
+// Test writing schemas.
+func TestWriteSchema(t *testing.T) {
+	t.Run("public", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_schema", nil)
+		w := httptest.NewRecorder()
+		rpc.WriteSchema(w, r, schemaServer{}, schemaDocument)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, `W/"1.0.0"`, w.Header().Get("ETag"))
+		assert.Equal(t, "Authorization", w.Header().Get("Vary"))
+		assert.Equal(t, `{"methods":[]}`, w.Body.String())
+	})
+
+	t.Run("private", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_schema", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		rpc.WriteSchema(w, r, schemaServer{}, schemaDocument)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, `W/"1.0.0+private"`, w.Header().Get("ETag"))
+		assert.Equal(t, `{"methods":[{"name":"reset"}]}`, w.Body.String())
+	})
+
+	t.Run("private without an authorizer", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_schema", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		rpc.WriteSchema(w, r, nil, schemaDocument)
+		assert.Equal(t, `{"methods":[]}`, w.Body.String())
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_schema", nil)
+		r.Header.Set("If-None-Match", `"0.9.0", W/"1.0.0"`)
+		w := httptest.NewRecorder()
+		rpc.WriteSchema(w, r, schemaServer{}, schemaDocument)
+		assert.Equal(t, 304, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_schema", nil)
+		r.Header.Set("If-None-Match", `"1.0.0"`)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		rpc.WriteSchema(w, r, schemaServer{}, schemaDocument)
+		assert.Equal(t, 200, w.Code)
+	})
+
+	t.Run("without a version", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_schema", nil)
+		r.Header.Set("If-None-Match", `*`)
+		w := httptest.NewRecorder()
+		rpc.WriteSchema(w, r, schemaServer{}, rpc.SchemaDocument{Public: []byte(`{}`)})
+		assert.Equal(t, 200, w.Code)
+		assert.Empty(t, w.Header().Get("ETag"))
+	})
+}
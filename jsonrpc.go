@@ -0,0 +1,322 @@
+package rpc
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	JSONRPCServerError    = -32000
+)
+
+// JSONRPCCodes are the JSON-RPC error codes of error types, where types not
+// present use JSONRPCServerError. Codes of application errors are in the
+// range reserved for server errors, following the HTTP status code where
+// possible, and may be added for custom error types.
+var JSONRPCCodes = map[string]int{
+	"bad_request":         JSONRPCInvalidParams,
+	"invalid":             JSONRPCInvalidParams,
+	"internal":            JSONRPCInternalError,
+	"unauthorized":        -32001,
+	"forbidden":           -32003,
+	"not_found":           -32004,
+	"conflict":            -32009,
+	"precondition_failed": -32012,
+	"rate_limited":        -32029,
+	"unavailable":         -32050,
+}
+
+// errStreamNotSupported is the cause of errJSONRPCStream.
+var errStreamNotSupported = errors.New("stream not supported")
+
+// errJSONRPCStream is the error of calls of streaming methods.
+var errJSONRPCStream = Wrap(errStreamNotSupported, http.StatusBadRequest, "bad_request", "Streaming methods are not supported over JSON-RPC")
+
+// jsonRPCRequest is a JSON-RPC request, which is a notification when it has no id.
+type jsonRPCRequest struct {
+	Version string             `json:"jsonrpc"`
+	Method  string             `json:"method"`
+	Params  stdjson.RawMessage `json:"params"`
+	ID      stdjson.RawMessage `json:"id"`
+}
+
+// jsonRPCResponse is a JSON-RPC response.
+type jsonRPCResponse struct {
+	Version string             `json:"jsonrpc"`
+	Result  stdjson.RawMessage `json:"result,omitempty"`
+	Error   *jsonRPCError      `json:"error,omitempty"`
+	ID      stdjson.RawMessage `json:"id"`
+}
+
+// jsonRPCError is a JSON-RPC error.
+type jsonRPCError struct {
+	Code    int               `json:"code"`
+	Message string            `json:"message"`
+	Data    *jsonRPCErrorData `json:"data,omitempty"`
+}
+
+// jsonRPCErrorData is the data of a JSON-RPC error, with the WriteError fields.
+type jsonRPCErrorData struct {
+	Status    int                    `json:"status"`
+	Type      string                 `json:"type"`
+	Fields    []ValidationError      `json:"fields,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// JSONRPCHandler is an http.Handler adapting JSON-RPC 2.0 requests, including
+// batches and notifications, to calls of the generated server Handler. Each
+// call is passed to Handler as a POST request to the method with the params
+// object as its body, so the method's limits, validation, interceptors and
+// logging apply as usual. Errors are responded with the code of their type
+// in JSONRPCCodes, and "data" containing the "status" code and the fields of
+// the WriteError shape. Positional params and streaming methods are not
+// supported, where streams are rejected by NewStream before they start,
+// and batches are limited to DefaultMaxBatchSize calls.
+//
+// Unknown methods are responded with JSONRPCMethodNotFound when the server
+// writes ErrInvalidMethod, or an error wrapping ErrMethodNotFound, using a
+// response writer returned by NewResponseWriter.
+type JSONRPCHandler struct {
+	Handler http.Handler
+
+	// MaxBodyBytes limits the size of request bodies, including batches,
+	// defaulting to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+}
+
+// ServeHTTP implementation.
+func (h *JSONRPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := h.MaxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if _, ok := err.(*http.MaxBytesError); ok {
+		writeJSONRPC(w, jsonRPCFailure(nil, JSONRPCInvalidRequest, fmt.Sprintf("Request body must not exceed %d bytes", limit)))
+		return
+	}
+
+	if err != nil {
+		writeJSONRPC(w, jsonRPCFailure(nil, JSONRPCParseError, "Parse error"))
+		return
+	}
+
+	b = bytes.TrimSpace(b)
+
+	// single request
+	if len(b) == 0 || b[0] != '[' {
+		res, ok := h.call(r, b)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSONRPC(w, res)
+		return
+	}
+
+	// batch request
+	var batch []stdjson.RawMessage
+	err = stdjson.Unmarshal(b, &batch)
+	if err != nil {
+		writeJSONRPC(w, jsonRPCFailure(nil, JSONRPCParseError, "Parse error"))
+		return
+	}
+
+	if len(batch) == 0 {
+		writeJSONRPC(w, jsonRPCFailure(nil, JSONRPCInvalidRequest, "Invalid Request"))
+		return
+	}
+
+	if len(batch) > DefaultMaxBatchSize {
+		writeJSONRPC(w, jsonRPCFailure(nil, JSONRPCInvalidRequest, fmt.Sprintf("Batch must not exceed %d calls", DefaultMaxBatchSize)))
+		return
+	}
+
+	var responses []jsonRPCResponse
+	for _, b := range batch {
+		if res, ok := h.call(r, b); ok {
+			responses = append(responses, res)
+		}
+	}
+
+	// only notifications
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSONRPC(w, responses)
+}
+
+// call invokes the JSON-RPC request b of request r, returning false for notifications.
+func (h *JSONRPCHandler) call(r *http.Request, b []byte) (jsonRPCResponse, bool) {
+	var req jsonRPCRequest
+	err := stdjson.Unmarshal(b, &req)
+	if err != nil {
+		if _, ok := err.(*stdjson.SyntaxError); ok {
+			return jsonRPCFailure(nil, JSONRPCParseError, "Parse error"), true
+		}
+		return jsonRPCFailure(nil, JSONRPCInvalidRequest, "Invalid Request"), true
+	}
+
+	if req.Version != "2.0" || req.Method == "" {
+		return jsonRPCFailure(req.ID, JSONRPCInvalidRequest, "Invalid Request"), true
+	}
+
+	notification := req.ID == nil
+
+	// internal endpoints such as _batch are not methods
+	if strings.HasPrefix(req.Method, "_") || strings.Contains(req.Method, "/") {
+		return jsonRPCFailure(req.ID, JSONRPCMethodNotFound, "Method not found"), !notification
+	}
+
+	params := bytes.TrimSpace(req.Params)
+	if len(params) > 0 && params[0] != '{' && string(params) != "null" {
+		return jsonRPCFailure(req.ID, JSONRPCInvalidParams, "Params must be an object"), !notification
+	}
+
+	// invoke, rejecting streams before they start, as
+	// their responses would be buffered until they end
+	sub := callRequest(withoutStreams(r, errJSONRPCStream), req.Method, params)
+	sub.Header.Set("Accept", "application/json")
+	sub.Header.Del("Accept-Encoding")
+
+	rec := &jsonRPCRecorder{header: make(http.Header)}
+	h.Handler.ServeHTTP(rec, sub)
+
+	if notification {
+		return jsonRPCResponse{}, false
+	}
+
+	return rec.response(req.ID), true
+}
+
+// jsonRPCFailure returns a JSON-RPC error response.
+func jsonRPCFailure(id stdjson.RawMessage, code int, message string) jsonRPCResponse {
+	return jsonRPCResponse{
+		Error: &jsonRPCError{
+			Code:    code,
+			Message: message,
+		},
+		ID: id,
+	}
+}
+
+// writeJSONRPC writes JSON-RPC response or batch response v.
+func writeJSONRPC(w http.ResponseWriter, v interface{}) {
+	b, err := stdjson.Marshal(v)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeBody(w, http.StatusOK, "application/json", append(b, '\n'))
+}
+
+// MarshalJSON implementation, writing the version, and a null id when missing.
+func (r jsonRPCResponse) MarshalJSON() ([]byte, error) {
+	type response jsonRPCResponse
+	r.Version = "2.0"
+	if r.ID == nil {
+		r.ID = stdjson.RawMessage("null")
+	}
+	return stdjson.Marshal(response(r))
+}
+
+// jsonRPCRecorder is a response writer recording the response of a call.
+type jsonRPCRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	err    error
+}
+
+// Header implementation.
+func (r *jsonRPCRecorder) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader implementation.
+func (r *jsonRPCRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// Write implementation.
+func (r *jsonRPCRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+// recordError implementation.
+func (r *jsonRPCRecorder) recordError(err error) {
+	r.err = err
+}
+
+// response returns the JSON-RPC response of the recorded response.
+func (r *jsonRPCRecorder) response(id stdjson.RawMessage) jsonRPCResponse {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// result
+	if status < 300 {
+		result := stdjson.RawMessage("null")
+		if body := bytes.TrimSpace(r.body.Bytes()); len(body) > 0 && status != http.StatusNoContent {
+			result = body
+		}
+		return jsonRPCResponse{Result: result, ID: id}
+	}
+
+	// error
+	var body ErrorResponse
+	if err := stdjson.Unmarshal(r.body.Bytes(), &body); err != nil || body.Type == "" {
+		body.Type = "internal"
+		body.Message = http.StatusText(status)
+	}
+
+	code, ok := JSONRPCCodes[body.Type]
+	if !ok {
+		code = JSONRPCServerError
+	}
+
+	switch {
+	case errors.Is(r.err, ErrMethodNotFound):
+		code = JSONRPCMethodNotFound
+	case errors.Is(r.err, errStreamNotSupported):
+		code = JSONRPCInvalidRequest
+	}
+
+	res := jsonRPCFailure(id, code, body.Message)
+	res.Error.Data = &jsonRPCErrorData{
+		Status:    status,
+		Type:      body.Type,
+		Fields:    body.Fields,
+		Details:   body.Details,
+		RequestID: body.RequestID,
+	}
+	return res
+}
@@ -0,0 +1,223 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// jsonRPCCall implementation.
+func jsonRPCCall(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (interface{}, error) {
+	switch path {
+	case "/stream":
+		stream, err := rpc.NewStream(w, r)
+		if err != nil {
+			return nil, err
+		}
+		return stream, stream.Send(map[string]interface{}{"item": "cook"})
+	case "/watch":
+		stream, err := rpc.NewStream(w, r)
+		if err != nil {
+			return nil, err
+		}
+		// streams until the client disconnects
+		stream.Send(map[string]interface{}{"item": "cook"})
+		<-ctx.Done()
+		return stream, nil
+	default:
+		return batchCall(ctx, w, r, path)
+	}
+}
+
+// jsonRPCServer is a server handling calls like a generated server.
+var jsonRPCServer = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	r = rpc.WithRequestID(r)
+	w = rpc.NewResponseWriter(w, r)
+	defer rpc.Recover(w, r)
+
+	res, err := jsonRPCCall(rpc.NewRequestContext(r.Context(), r), w, r, r.URL.Path)
+	if err != nil {
+		rpc.WriteError(w, err)
+		return
+	}
+
+	rpc.WriteResponse(w, res)
+})
+
+// serveJSONRPC serves a JSON-RPC request with body.
+func serveJSONRPC(body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Request-ID", "abc")
+	w := httptest.NewRecorder()
+	h := &rpc.JSONRPCHandler{Handler: jsonRPCServer}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// Test JSON-RPC requests.
+func TestJSONRPCHandler(t *testing.T) {
+	defer func(fn func(*http.Request, rpc.PanicError)) {
+		rpc.OnPanic = fn
+	}(rpc.OnPanic)
+	rpc.OnPanic = nil
+
+	t.Run("with a result", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "get_items", "id": 1 }`)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "result": { "items": ["cook"] }, "id": 1 }`, w.Body.String())
+	})
+
+	t.Run("with no result", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "add_item", "params": { "item": "cook" }, "id": "a" }`)
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "result": null, "id": "a" }`, w.Body.String())
+	})
+
+	t.Run("with a validation error", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "add_item", "params": {}, "id": 1 }`)
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{
+			"jsonrpc": "2.0",
+			"error": {
+				"code": -32602,
+				"message": "item is required",
+				"data": {
+					"status": 400,
+					"type": "invalid",
+					"fields": [{ "field": "item", "message": "is required" }],
+					"request_id": "abc"
+				}
+			},
+			"id": 1
+		}`, w.Body.String())
+	})
+
+	t.Run("with an unknown method", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "remove_item", "id": 1 }`)
+		var res struct {
+			Error struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, rpc.JSONRPCMethodNotFound, res.Error.Code)
+		assert.Equal(t, "Invalid method", res.Error.Message)
+	})
+
+	t.Run("with an internal method", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "_batch", "params": [], "id": 1 }`)
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "error": { "code": -32601, "message": "Method not found" }, "id": 1 }`, w.Body.String())
+	})
+
+	t.Run("with a panic", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "panic", "id": 1 }`)
+		assert.JSONEq(t, `{
+			"jsonrpc": "2.0",
+			"error": {
+				"code": -32603,
+				"message": "Internal server error",
+				"data": { "status": 500, "type": "internal", "request_id": "abc" }
+			},
+			"id": 1
+		}`, w.Body.String())
+	})
+
+	t.Run("with positional params", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "add_item", "params": ["cook"], "id": 1 }`)
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "error": { "code": -32602, "message": "Params must be an object" }, "id": 1 }`, w.Body.String())
+	})
+
+	t.Run("with a stream", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "stream", "id": 1 }`)
+		assert.JSONEq(t, `{
+			"jsonrpc": "2.0",
+			"error": {
+				"code": -32600,
+				"message": "Streaming methods are not supported over JSON-RPC",
+				"data": { "status": 400, "type": "bad_request", "request_id": "abc" }
+			},
+			"id": 1
+		}`, w.Body.String())
+	})
+
+	t.Run("with an unbounded stream", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- serveJSONRPC(`{ "jsonrpc": "2.0", "method": "watch", "id": 1 }`)
+		}()
+
+		select {
+		case w := <-done:
+			assert.Contains(t, w.Body.String(), "Streaming methods are not supported over JSON-RPC")
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream was invoked")
+		}
+	})
+
+	t.Run("with a notification", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method": "add_item", "params": { "item": "cook" } }`)
+		assert.Equal(t, 204, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("with a batch", func(t *testing.T) {
+		w := serveJSONRPC(`[
+			{ "jsonrpc": "2.0", "method": "get_items", "id": 1 },
+			{ "jsonrpc": "2.0", "method": "add_item", "params": { "item": "cook" } },
+			{ "jsonrpc": "2.0", "method": "not_found", "id": 2 },
+			{ "jsonrpc": "1.0", "method": "get_items", "id": 3 },
+			1
+		]`)
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `[
+			{ "jsonrpc": "2.0", "result": { "items": ["cook"] }, "id": 1 },
+			{ "jsonrpc": "2.0", "error": { "code": -32601, "message": "Invalid method", "data": { "status": 400, "type": "bad_request", "request_id": "abc" } }, "id": 2 },
+			{ "jsonrpc": "2.0", "error": { "code": -32600, "message": "Invalid Request" }, "id": 3 },
+			{ "jsonrpc": "2.0", "error": { "code": -32600, "message": "Invalid Request" }, "id": null }
+		]`, w.Body.String())
+	})
+
+	t.Run("with a batch of notifications", func(t *testing.T) {
+		w := serveJSONRPC(`[{ "jsonrpc": "2.0", "method": "get_items" }]`)
+		assert.Equal(t, 204, w.Code)
+	})
+
+	t.Run("with an empty batch", func(t *testing.T) {
+		w := serveJSONRPC(`[]`)
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "error": { "code": -32600, "message": "Invalid Request" }, "id": null }`, w.Body.String())
+	})
+
+	t.Run("with malformed json", func(t *testing.T) {
+		w := serveJSONRPC(`{ "jsonrpc": "2.0", "method"`)
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "error": { "code": -32700, "message": "Parse error" }, "id": null }`, w.Body.String())
+
+		w = serveJSONRPC(`[{ "jsonrpc": "2.0" `)
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "error": { "code": -32700, "message": "Parse error" }, "id": null }`, w.Body.String())
+	})
+
+	t.Run("with a body exceeding the limit", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "jsonrpc": "2.0", "method": "get_items", "id": 1 }`))
+		w := httptest.NewRecorder()
+		(&rpc.JSONRPCHandler{Handler: jsonRPCServer, MaxBodyBytes: 10}).ServeHTTP(w, r)
+		assert.JSONEq(t, `{ "jsonrpc": "2.0", "error": { "code": -32600, "message": "Request body must not exceed 10 bytes" }, "id": null }`, w.Body.String())
+	})
+
+	t.Run("with a GET request", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		(&rpc.JSONRPCHandler{Handler: jsonRPCServer}).ServeHTTP(w, r)
+		assert.Equal(t, 405, w.Code)
+		assert.Equal(t, "POST", w.Header().Get("Allow"))
+	})
+}
@@ -0,0 +1,19 @@
+package rpc
+
+import (
+	"context"
+)
+
+// Fields are structured log fields.
+type Fields map[string]interface{}
+
+// Logger is the interface used by generated servers for per-method logging,
+// see the logger/apexlog and logger/slogger packages for implementations.
+type Logger interface {
+	// WithFields returns a logger with fields added.
+	WithFields(fields Fields) Logger
+
+	// NewContext returns a new context with the logger, which method
+	// implementations retrieve using the underlying logging package.
+	NewContext(ctx context.Context) context.Context
+}
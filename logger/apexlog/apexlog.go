@@ -0,0 +1,35 @@
+// Package apexlog provides an rpc.Logger implementation for github.com/apex/log.
+package apexlog
+
+import (
+	"context"
+
+	"github.com/apex/log"
+
+	"github.com/apex/rpc"
+)
+
+// Logger is an apex/log logger.
+type Logger struct {
+	log.Interface
+}
+
+// New returns a new logger.
+func New(l log.Interface) Logger {
+	return Logger{l}
+}
+
+// FromContext returns the logger from context, defaulting to log.Log.
+func FromContext(ctx context.Context) Logger {
+	return Logger{log.FromContext(ctx)}
+}
+
+// WithFields implementation.
+func (l Logger) WithFields(fields rpc.Fields) rpc.Logger {
+	return Logger{l.Interface.WithFields(log.Fields(fields))}
+}
+
+// NewContext implementation, the logger is retrieved with log.FromContext.
+func (l Logger) NewContext(ctx context.Context) context.Context {
+	return log.NewContext(ctx, l.Interface)
+}
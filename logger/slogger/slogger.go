@@ -0,0 +1,57 @@
+// Package slogger provides an rpc.Logger implementation for log/slog.
+package slogger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/apex/rpc"
+)
+
+// Logger is a log/slog logger.
+type Logger struct {
+	*slog.Logger
+}
+
+// New returns a new logger.
+func New(l *slog.Logger) Logger {
+	return Logger{l}
+}
+
+// loggerKey is a private context key.
+type loggerKey struct{}
+
+// NewContext returns a new context with logger l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger from context, defaulting to slog.Default().
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return Logger{l}
+	}
+	return Logger{slog.Default()}
+}
+
+// WithFields implementation, fields are added in key order.
+func (l Logger) WithFields(fields rpc.Fields) rpc.Logger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]interface{}, 0, len(fields)*2)
+	for _, k := range keys {
+		args = append(args, k, fields[k])
+	}
+
+	return Logger{l.Logger.With(args...)}
+}
+
+// NewContext implementation, the logger is retrieved with FromContext.
+func (l Logger) NewContext(ctx context.Context) context.Context {
+	return NewContext(ctx, l.Logger)
+}
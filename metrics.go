@@ -0,0 +1,195 @@
+package rpc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the default latency histogram buckets, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultMetrics are the metrics recorded by generated servers, served at
+// /_metrics when generated with the Metrics option.
+var DefaultMetrics = NewMetrics()
+
+// Metrics records per-method request counts by error type, latency
+// histograms, and in-flight gauges.
+type Metrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	methods map[string]*methodMetrics
+}
+
+// methodMetrics are the metrics of a single method.
+type methodMetrics struct {
+	inFlight int64
+	requests map[string]uint64
+	counts   []uint64
+	sum      float64
+	count    uint64
+}
+
+// NewMetrics returns new metrics with latency histogram buckets
+// in seconds, defaulting to DefaultBuckets.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Metrics{
+		buckets: buckets,
+		methods: map[string]*methodMetrics{},
+	}
+}
+
+// method returns the metrics for method name, creating them if necessary.
+// The mutex must be held.
+func (m *Metrics) method(name string) *methodMetrics {
+	mm, ok := m.methods[name]
+	if !ok {
+		mm = &methodMetrics{
+			requests: map[string]uint64{},
+			counts:   make([]uint64, len(m.buckets)),
+		}
+		m.methods[name] = mm
+	}
+	return mm
+}
+
+// Observe records the start of an invocation of method, returning a function
+// which must be deferred to record its end with the error pointed to by err.
+// Panics are recorded as internal errors, and then propagated.
+//
+//	defer rpc.DefaultMetrics.Observe("add_item")(&err)
+func (m *Metrics) Observe(method string) func(err *error) {
+	start := time.Now()
+
+	m.mu.Lock()
+	m.method(method).inFlight++
+	m.mu.Unlock()
+
+	return func(err *error) {
+		if v := recover(); v != nil {
+			m.record(method, start, "internal")
+			panic(v)
+		}
+
+		kind := "none"
+		if *err != nil {
+			kind = errorType(*err)
+		}
+
+		m.record(method, start, kind)
+	}
+}
+
+// record the end of an invocation of method.
+func (m *Metrics) record(method string, start time.Time, kind string) {
+	d := time.Since(start).Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mm := m.method(method)
+	mm.inFlight--
+	mm.requests[kind]++
+	mm.sum += d
+	mm.count++
+
+	for i, le := range m.buckets {
+		if d <= le {
+			mm.counts[i]++
+			break
+		}
+	}
+}
+
+// WriteMetrics writes metrics m in the Prometheus text exposition format.
+func WriteMetrics(w http.ResponseWriter, m *Metrics) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for name := range m.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	// requests
+	fmt.Fprintf(out, "# HELP rpc_requests_total Total number of requests by method and error type.\n")
+	fmt.Fprintf(out, "# TYPE rpc_requests_total counter\n")
+	for _, name := range names {
+		mm := m.methods[name]
+
+		var kinds []string
+		for kind := range mm.requests {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			fmt.Fprintf(out, "rpc_requests_total{method=%s,error=%s} %d\n", label(name), label(kind), mm.requests[kind])
+		}
+	}
+
+	// latency
+	fmt.Fprintf(out, "# HELP rpc_request_duration_seconds Request latency in seconds by method.\n")
+	fmt.Fprintf(out, "# TYPE rpc_request_duration_seconds histogram\n")
+	for _, name := range names {
+		mm := m.methods[name]
+
+		var n uint64
+		for i, le := range m.buckets {
+			n += mm.counts[i]
+			fmt.Fprintf(out, "rpc_request_duration_seconds_bucket{method=%s,le=\"%s\"} %d\n", label(name), formatFloat(le), n)
+		}
+
+		fmt.Fprintf(out, "rpc_request_duration_seconds_bucket{method=%s,le=\"+Inf\"} %d\n", label(name), mm.count)
+		fmt.Fprintf(out, "rpc_request_duration_seconds_sum{method=%s} %s\n", label(name), formatFloat(mm.sum))
+		fmt.Fprintf(out, "rpc_request_duration_seconds_count{method=%s} %d\n", label(name), mm.count)
+	}
+
+	// in-flight
+	fmt.Fprintf(out, "# HELP rpc_requests_in_flight Number of requests in flight by method.\n")
+	fmt.Fprintf(out, "# TYPE rpc_requests_in_flight gauge\n")
+	for _, name := range names {
+		fmt.Fprintf(out, "rpc_requests_in_flight{method=%s} %d\n", label(name), m.methods[name].inFlight)
+	}
+}
+
+// errorType returns the type of err, defaulting to "internal".
+func errorType(err error) string {
+	var tp TypeProvider
+	if errors.As(err, &tp) {
+		return tp.Type()
+	}
+	return "internal"
+}
+
+// labelEscaper escapes Prometheus label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label returns a quoted Prometheus label value.
+func label(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+// formatFloat returns a formatted float.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,47 @@
+package rpc_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test metrics.
+func TestMetrics(t *testing.T) {
+	m := rpc.NewMetrics(0.5, 1)
+
+	invoke := func(method string, err error) {
+		defer m.Observe(method)(&err)
+	}
+
+	invoke("add_item", nil)
+	invoke("add_item", nil)
+	invoke("add_item", rpc.Invalid("Item is required"))
+	invoke("get_items", errors.New("boom"))
+
+	t.Run("with a panic", func(t *testing.T) {
+		assert.Panics(t, func() {
+			var err error
+			defer m.Observe("get_items")(&err)
+			panic("boom")
+		})
+	})
+
+	w := httptest.NewRecorder()
+	rpc.WriteMetrics(w, m)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE rpc_requests_total counter\n")
+	assert.Contains(t, body, `rpc_requests_total{method="add_item",error="invalid"} 1`+"\n")
+	assert.Contains(t, body, `rpc_requests_total{method="add_item",error="none"} 2`+"\n")
+	assert.Contains(t, body, `rpc_requests_total{method="get_items",error="internal"} 2`+"\n")
+	assert.Contains(t, body, `rpc_request_duration_seconds_bucket{method="add_item",le="0.5"} 3`+"\n")
+	assert.Contains(t, body, `rpc_request_duration_seconds_bucket{method="add_item",le="+Inf"} 3`+"\n")
+	assert.Contains(t, body, `rpc_request_duration_seconds_count{method="get_items"} 2`+"\n")
+	assert.Contains(t, body, `rpc_requests_in_flight{method="add_item"} 0`+"\n")
+}
@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// PanicError is an error recovered from a panic in a method.
+type PanicError struct {
+	Method string
+	Value  interface{}
+	Stack  []byte
+}
+
+// Error implementation.
+func (e PanicError) Error() string {
+	return fmt.Sprintf("panic in method %q: %v", e.Method, e.Value)
+}
+
+// OnPanic is called with panics recovered by Recover, and defaults
+// to logging the panic and stack with the standard logger.
+var OnPanic = func(r *http.Request, e PanicError) {
+	log.Printf("rpc: %s\n%s", e.Error(), e.Stack)
+}
+
+// Recover recovers a panic in the handling of request r, passing it to
+// OnPanic and responding with an internal error. It must be deferred.
+func Recover(w http.ResponseWriter, r *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	// let net/http abort the response
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+
+	WriteError(w, panicError(r, v))
+}
+
+// panicError passes the panic value v recovered in the handling
+// of request r to OnPanic, returning an internal error.
+func panicError(r *http.Request, v interface{}) error {
+	e := PanicError{
+		Method: strings.TrimPrefix(r.URL.Path, "/"),
+		Value:  v,
+		Stack:  debug.Stack(),
+	}
+
+	if OnPanic != nil {
+		OnPanic(r, e)
+	}
+
+	return Wrap(e, http.StatusInternalServerError, "internal", "Internal server error")
+}
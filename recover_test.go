@@ -0,0 +1,40 @@
+package rpc_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test panic recovery.
+func TestRecover(t *testing.T) {
+	var recovered rpc.PanicError
+	defer func(fn func(*http.Request, rpc.PanicError)) {
+		rpc.OnPanic = fn
+	}(rpc.OnPanic)
+
+	rpc.OnPanic = func(r *http.Request, e rpc.PanicError) {
+		recovered = e
+	}
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		defer rpc.Recover(w, r)
+		panic("boom")
+	}
+
+	r := httptest.NewRequest("POST", "/add_item", nil)
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, "{\n  \"type\": \"internal\",\n  \"message\": \"Internal server error\"\n}", strings.TrimSpace(w.Body.String()))
+	assert.Equal(t, "add_item", recovered.Method)
+	assert.Equal(t, "boom", recovered.Value)
+	assert.Contains(t, string(recovered.Stack), "TestRecover")
+	assert.EqualError(t, recovered, `panic in method "add_item": boom`)
+}
@@ -1,33 +1,506 @@
 package rpc
 
 import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	jsoniter "github.com/json-iterator/go"
 )
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
-// ReadRequest parses application/json request bodies into value, or returns an error.
-func ReadRequest(r *http.Request, value interface{}) error {
-	switch r.Header.Get("Content-Type") {
-	case "application/json":
-		// decode
-		err := json.NewDecoder(r.Body).Decode(value)
+// ReadOption is a ReadRequest option.
+type ReadOption func(*readConfig)
+
+// readConfig is the ReadRequest configuration.
+type readConfig struct {
+	strict          bool
+	maxBodyBytes    int64
+	maxInflated     int64
+	maxDepth        int
+	maxArrayLength  int
+	maxStringLength int
+}
+
+// structural returns true if the body must be walked before decoding.
+func (c readConfig) structural() bool {
+	return c.strict || c.maxDepth > 0 || c.maxArrayLength > 0 || c.maxStringLength > 0
+}
+
+// Strict rejects request bodies containing unknown fields, duplicate keys or
+// trailing data. Bodies of codecs other than JSON are checked for unknown
+// fields and keys duplicated case-insensitively, while exact duplicates and
+// trailing data are handled as the codec decodes them.
+func Strict() ReadOption {
+	return func(c *readConfig) {
+		c.strict = true
+	}
+}
+
+// DefaultMaxBodyBytes is the default limit of request bodies containing
+// multiple calls, such as batch and JSON-RPC requests.
+const DefaultMaxBodyBytes = 32 << 20
+
+// MaxBodyBytes limits the size of request bodies, responding with 413 when exceeded.
+func MaxBodyBytes(n int64) ReadOption {
+	return func(c *readConfig) {
+		c.maxBodyBytes = n
+	}
+}
+
+// MaxDecompressedBytes limits the size of compressed request bodies once
+// decompressed, responding with 413 when exceeded. It defaults to
+// DefaultMaxDecompressedBytes.
+func MaxDecompressedBytes(n int64) ReadOption {
+	return func(c *readConfig) {
+		c.maxInflated = n
+	}
+}
+
+// MaxDepth limits the nesting depth of objects and arrays in request bodies.
+func MaxDepth(n int) ReadOption {
+	return func(c *readConfig) {
+		c.maxDepth = n
+	}
+}
+
+// MaxArrayLength limits the length of arrays in request bodies.
+func MaxArrayLength(n int) ReadOption {
+	return func(c *readConfig) {
+		c.maxArrayLength = n
+	}
+}
+
+// MaxStringLength limits the length of strings in request bodies, in characters.
+func MaxStringLength(n int) ReadOption {
+	return func(c *readConfig) {
+		c.maxStringLength = n
+	}
+}
+
+// ReadRequest parses request bodies into value using the codec registered
+// for the Content-Type, or returns an error. Bodies with a gzip or zstd
+// Content-Encoding are decompressed. The strict and structural limit
+// options apply to bodies of every codec, where bodies of codecs other
+// than JSON are decoded generically to be checked.
+func ReadRequest(r *http.Request, value interface{}, options ...ReadOption) error {
+	c := readConfig{
+		maxInflated: DefaultMaxDecompressedBytes,
+	}
+
+	for _, o := range options {
+		o(&c)
+	}
+
+	codec, ok := LookupCodec(r.Header.Get("Content-Type"))
+	if !ok {
+		return BadRequest("Unsupported request Content-Type, must be " + oneOf(contentTypes()))
+	}
+
+	// limit
+	var body io.Reader = r.Body
+	var limited io.Reader
+	if c.maxBodyBytes > 0 {
+		if r.ContentLength > c.maxBodyBytes {
+			return tooLarge(c.maxBodyBytes)
+		}
+		limited = http.MaxBytesReader(nil, r.Body, c.maxBodyBytes)
+		body = limited
+	}
+
+	// decompress
+	var inflated io.Reader
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		d, ok, err := decompressor(body, encoding)
+		if !ok {
+			return BadRequest("Unsupported request Content-Encoding, must be gzip or zstd")
+		}
+
+		if err != nil && exceeded(limited) {
+			return tooLarge(c.maxBodyBytes)
+		}
+
+		if err != nil {
+			return BadRequest("Failed to decompress malformed request body")
+		}
+		defer d.Close()
+
+		inflated = http.MaxBytesReader(nil, d, c.maxInflated)
+		body = inflated
+	}
+
+	// decode
+	var err error
+	switch {
+	case codec.ContentType() != "application/json" && c.structural():
+		err = decodeCodecChecked(body, value, codec, c)
+	case codec.ContentType() != "application/json":
+		err = decodeCodec(body, value, codec)
+	case c.structural():
+		err = decodeChecked(body, value, c)
+	default:
+		err = json.NewDecoder(body).Decode(value)
+	}
+
+	if exceeded(limited) {
+		return tooLarge(c.maxBodyBytes)
+	}
+
+	if exceeded(inflated) {
+		return Error(http.StatusRequestEntityTooLarge, "request_too_large", fmt.Sprintf("Decompressed request body must not exceed %d bytes", c.maxInflated))
+	}
+
+	if _, ok := err.(ServerError); ok {
+		return err
+	}
+
+	if err != nil {
+		return BadRequest(fmt.Sprintf("Failed to parse malformed request body, must be a valid %s object", formatName(codec)))
+	}
+
+	// validate
+	if v, ok := value.(Validator); ok {
+		err := v.Validate()
 		if err != nil {
-			return BadRequest("Failed to parse malformed request body, must be a valid JSON object")
+			return validationError(err)
+		}
+	}
+
+	return nil
+}
+
+// decodeCodec decodes r into value using codec.
+func decodeCodec(r io.Reader, value interface{}, codec Codec) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(b, value)
+}
+
+// decodeCodecChecked decodes r into value using codec after checking the
+// structure of its generic decoding, returning an invalid error when a check fails.
+func decodeCodecChecked(r io.Reader, value interface{}, codec Codec, c readConfig) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var v interface{}
+	err = codec.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+
+	err = checkGeneric(reflect.ValueOf(v), reflect.TypeOf(value), "", 1, c)
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(b, value)
+}
+
+// formatName returns the name of a codec's format used in error messages.
+func formatName(c Codec) string {
+	switch c.ContentType() {
+	case "application/json":
+		return "JSON"
+	default:
+		return c.ContentType()
+	}
+}
+
+// tooLarge returns a request body size error.
+func tooLarge(n int64) error {
+	return Error(http.StatusRequestEntityTooLarge, "request_too_large", fmt.Sprintf("Request body must not exceed %d bytes", n))
+}
+
+// exceeded returns true if r, returned by http.MaxBytesReader, exceeded its limit.
+func exceeded(r io.Reader) bool {
+	if r == nil {
+		return false
+	}
+
+	// the error of an exceeded limit is returned by subsequent reads
+	var e *http.MaxBytesError
+	_, err := r.Read(nil)
+	return errors.As(err, &e)
+}
+
+// decodeChecked decodes r into value after checking its structure,
+// returning an invalid error when a check fails.
+func decodeChecked(r io.Reader, value interface{}, c readConfig) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// structure
+	dec := stdjson.NewDecoder(bytes.NewReader(b))
+	err = checkValue(dec, reflect.TypeOf(value), "", 1, c)
+	if err != nil {
+		return err
+	}
+
+	if !c.strict {
+		return json.Unmarshal(b, value)
+	}
+
+	// trailing data
+	if _, err := dec.Token(); err != io.EOF {
+		return Invalid("Unexpected data after the request body")
+	}
+
+	// unknown fields
+	dec = stdjson.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(value)
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return Invalid(fmt.Sprintf("Unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field ")))
+	}
+
+	return err
+}
+
+// checkValue walks the next JSON value of dec decoded into type typ, returning
+// an invalid error for duplicate keys in strict mode, or exceeded limits. Keys
+// of struct types are compared by the field they decode into, which like
+// encoding/json matches case-insensitively.
+func checkValue(dec *stdjson.Decoder, typ reflect.Type, path string, depth int, c readConfig) error {
+	typ = indirect(typ)
+
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	// depth
+	if t == stdjson.Delim('{') || t == stdjson.Delim('[') {
+		if c.maxDepth > 0 && depth > c.maxDepth {
+			return limitError(path, "nesting depth", c.maxDepth)
+		}
+	}
+
+	switch t := t.(type) {
+	case string:
+		if c.maxStringLength > 0 && utf8.RuneCountInString(t) > c.maxStringLength {
+			return limitError(path, "string length", c.maxStringLength)
+		}
+	case stdjson.Delim:
+		switch t {
+		case '{':
+			keys := map[string]bool{}
+			for dec.More() {
+				t, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				key := t.(string)
+				field := key
+				if path != "" {
+					field = path + "." + key
+				}
+
+				name, elem := key, reflect.Type(nil)
+				switch {
+				case typ == nil:
+				case typ.Kind() == reflect.Struct:
+					if n, t, ok := jsonField(typ, key); ok {
+						name, elem = n, t
+					}
+				case typ.Kind() == reflect.Map:
+					elem = typ.Elem()
+				}
+
+				if c.strict && keys[name] {
+					return Invalid(fmt.Sprintf("Duplicate field %q", field))
+				}
+				keys[name] = true
+
+				err = checkValue(dec, elem, field, depth+1, c)
+				if err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		case '[':
+			var elem reflect.Type
+			if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+				elem = typ.Elem()
+			}
+
+			for i := 0; dec.More(); i++ {
+				if c.maxArrayLength > 0 && i >= c.maxArrayLength {
+					return limitError(path, "array length", c.maxArrayLength)
+				}
+
+				err := checkValue(dec, elem, fmt.Sprintf("%s[%d]", path, i), depth+1, c)
+				if err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkGeneric walks the generically decoded value v decoded into type typ,
+// returning an invalid error for unknown fields or keys duplicated by case
+// in strict mode, or exceeded limits, like checkValue for JSON.
+func checkGeneric(v reflect.Value, typ reflect.Type, path string, depth int, c readConfig) error {
+	typ = indirect(typ)
+
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	// depth
+	if v.Kind() == reflect.Map || v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		if c.maxDepth > 0 && depth > c.maxDepth {
+			return limitError(path, "nesting depth", c.maxDepth)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if c.maxStringLength > 0 && utf8.RuneCountInString(v.String()) > c.maxStringLength {
+			return limitError(path, "string length", c.maxStringLength)
 		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
 
-		// validate
-		if v, ok := value.(Validator); ok {
-			err := v.Validate()
+		names := map[string]bool{}
+		for _, k := range keys {
+			key := fmt.Sprint(k)
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+
+			name, elem := key, reflect.Type(nil)
+			switch {
+			case typ == nil:
+			case typ.Kind() == reflect.Struct:
+				n, t, ok := jsonField(typ, key)
+				if !ok && c.strict {
+					return Invalid(fmt.Sprintf("Unknown field %q", key))
+				}
+				name, elem = n, t
+			case typ.Kind() == reflect.Map:
+				elem = typ.Elem()
+			}
+
+			if c.strict && names[name] {
+				return Invalid(fmt.Sprintf("Duplicate field %q", field))
+			}
+			names[name] = true
+
+			err := checkGeneric(v.MapIndex(k), elem, field, depth+1, c)
 			if err != nil {
-				return Invalid(err.Error())
+				return err
 			}
 		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
 
-		return nil
-	default:
-		return BadRequest("Unsupported request Content-Type, must be application/json")
+		var elem reflect.Type
+		if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+			elem = typ.Elem()
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			if c.maxArrayLength > 0 && i >= c.maxArrayLength {
+				return limitError(path, "array length", c.maxArrayLength)
+			}
+
+			err := checkGeneric(v.Index(i), elem, fmt.Sprintf("%s[%d]", path, i), depth+1, c)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// limitError returns an invalid error for the limit n exceeded by the field at
+// path, or the request body itself when path is empty.
+func limitError(path, limit string, n int) error {
+	subject := "Request body"
+	if path != "" {
+		subject = fmt.Sprintf("Field %q", path)
+	}
+	return Invalid(fmt.Sprintf("%s exceeds the maximum %s of %d", subject, limit, n))
+}
+
+// jsonField returns the name and type of the field of struct type t which
+// the JSON object key decodes into, preferring an exact match.
+func jsonField(t reflect.Type, key string) (string, reflect.Type, bool) {
+	var name string
+	var typ reflect.Type
+	var ok bool
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		n, _, _ := strings.Cut(tag, ",")
+
+		// embedded structs
+		if f.Anonymous && n == "" {
+			if e := indirect(f.Type); e.Kind() == reflect.Struct {
+				if n, t, found := jsonField(e, key); found && (n == key || !ok) {
+					name, typ, ok = n, t, true
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if n == "" {
+			n = f.Name
+		}
+
+		if n == key {
+			return n, f.Type, true
+		}
+
+		if !ok && strings.EqualFold(n, key) {
+			name, typ, ok = n, f.Type, true
+		}
+	}
+
+	return name, typ, ok
+}
+
+// indirect returns the type t points to, or t itself.
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
 	}
+	return t
 }
@@ -1,10 +1,13 @@
 package rpc_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/tj/assert"
 
 	"github.com/apex/rpc"
@@ -45,6 +48,208 @@ func TestReadRequest(t *testing.T) {
 	})
 }
 
+// Test strict requests.
+func TestReadRequest_strict(t *testing.T) {
+	type input struct {
+		Name string `json:"name"`
+		Pets []struct {
+			Name string `json:"name"`
+		} `json:"pets"`
+	}
+
+	t.Run("with a valid body", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi", "pets": [{ "name": "Loki" }] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.Strict())
+		assert.NoError(t, err, "parsing")
+		assert.Equal(t, "Loki", in.Pets[0].Name)
+	})
+
+	t.Run("with an unknown field", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "nmae": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.Strict())
+		assert.EqualError(t, err, `Unknown field "nmae"`)
+		assert.Equal(t, "invalid", err.(rpc.TypeProvider).Type())
+	})
+
+	t.Run("with a duplicate key", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "pets": [{ "name": "Loki" }, { "name": "Jane", "name": "Tobi" }] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.Strict())
+		assert.EqualError(t, err, `Duplicate field "pets[1].name"`)
+	})
+
+	t.Run("with a mixed-case duplicate key", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "pets": [{ "name": "Jane", "Name": "Tobi" }] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.Strict())
+		assert.EqualError(t, err, `Duplicate field "pets[0].Name"`)
+	})
+
+	t.Run("with mixed-case map keys", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "meta": { "name": "Jane", "Name": "Tobi" } }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in struct {
+			Meta map[string]string `json:"meta"`
+		}
+		err := rpc.ReadRequest(r, &in, rpc.Strict())
+		assert.NoError(t, err, "parsing")
+		assert.Equal(t, "Tobi", in.Meta["Name"])
+	})
+
+	t.Run("with trailing data", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" } {}`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.Strict())
+		assert.EqualError(t, err, `Unexpected data after the request body`)
+	})
+
+	t.Run("with malformed JSON", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.Strict())
+		assert.EqualError(t, err, `Failed to parse malformed request body, must be a valid JSON object`)
+	})
+}
+
+// Test request limits.
+func TestReadRequest_limits(t *testing.T) {
+	type input struct {
+		Name string                 `json:"name"`
+		Tags []string               `json:"tags"`
+		Meta map[string]interface{} `json:"meta"`
+	}
+
+	t.Run("with a body within the limits", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi", "tags": ["ferret"], "meta": { "age": 5 } }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.MaxBodyBytes(1024), rpc.MaxDepth(2), rpc.MaxArrayLength(1), rpc.MaxStringLength(6))
+		assert.NoError(t, err, "parsing")
+		assert.Equal(t, "Tobi", in.Name)
+	})
+
+	t.Run("with a body exceeding the content-length limit", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.MaxBodyBytes(10))
+		assert.EqualError(t, err, `Request body must not exceed 10 bytes`)
+		assert.Equal(t, 413, err.(rpc.StatusProvider).StatusCode())
+	})
+
+	t.Run("with a streamed body exceeding the limit", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.ContentLength = -1
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.MaxBodyBytes(10))
+		assert.EqualError(t, err, `Request body must not exceed 10 bytes`)
+		assert.Equal(t, 413, err.(rpc.StatusProvider).StatusCode())
+	})
+
+	t.Run("with a body exceeding the depth", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "meta": { "owner": { "name": "Tobi" } } }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.MaxDepth(2))
+		assert.EqualError(t, err, `Field "meta.owner" exceeds the maximum nesting depth of 2`)
+		assert.Equal(t, "invalid", err.(rpc.TypeProvider).Type())
+	})
+
+	t.Run("with an array exceeding the length", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "tags": ["ferret", "pet"] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.MaxArrayLength(1))
+		assert.EqualError(t, err, `Field "tags" exceeds the maximum array length of 1`)
+	})
+
+	t.Run("with a string exceeding the length", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "tags": ["ferret", "animal"] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in input
+		err := rpc.ReadRequest(r, &in, rpc.MaxStringLength(4))
+		assert.EqualError(t, err, `Field "tags[0]" exceeds the maximum string length of 4`)
+	})
+
+	t.Run("with a root array exceeding the length", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`["ferret", "pet"]`))
+		r.Header.Set("Content-Type", "application/json")
+		var in []string
+		err := rpc.ReadRequest(r, &in, rpc.MaxArrayLength(1))
+		assert.EqualError(t, err, `Request body exceeds the maximum array length of 1`)
+	})
+}
+
+// Test compressed requests.
+func TestReadRequest_compressed(t *testing.T) {
+	gzipped := func(s string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(s))
+		gz.Close()
+		return &buf
+	}
+
+	t.Run("with a gzip body", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", gzipped(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "gzip")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.NoError(t, err, "parsing")
+		assert.Equal(t, "Tobi", in.Name)
+	})
+
+	t.Run("with a zstd body", func(t *testing.T) {
+		enc, _ := zstd.NewWriter(nil)
+		b := enc.EncodeAll([]byte(`{ "name": "Tobi" }`), nil)
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "zstd")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.NoError(t, err, "parsing")
+		assert.Equal(t, "Tobi", in.Name)
+	})
+
+	t.Run("with an unsupported encoding", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "br")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.EqualError(t, err, `Unsupported request Content-Encoding, must be gzip or zstd`)
+	})
+
+	t.Run("with a malformed gzip body", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "gzip")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.EqualError(t, err, `Failed to decompress malformed request body`)
+	})
+
+	t.Run("with a body exceeding the decompressed limit", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", gzipped(`{ "name": "`+strings.Repeat("a", 1000)+`" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "gzip")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in, rpc.MaxBodyBytes(100), rpc.MaxDecompressedBytes(500))
+		assert.EqualError(t, err, `Decompressed request body must not exceed 500 bytes`)
+		assert.Equal(t, 413, err.(rpc.StatusProvider).StatusCode())
+	})
+}
+
 // Benchmark requests.
 func BenchmarkReadRequest(b *testing.B) {
 	b.ReportAllocs()
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header used for propagating request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is a private context key.
+type requestIDKey struct{}
+
+// NewRequestIDContext returns a new context with request ID id.
+func NewRequestIDContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID from context.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(requestIDKey{}).(string)
+	return v, ok
+}
+
+// WithRequestID returns a shallow copy of r with a context containing the request
+// ID provided by the X-Request-ID header, or a generated request ID when missing
+// or malformed. NewResponseWriter echoes the request ID in the response header,
+// and WriteError in error bodies.
+func WithRequestID(r *http.Request) *http.Request {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = NewRequestID()
+	}
+	return r.WithContext(NewRequestIDContext(r.Context(), id))
+}
+
+// NewRequestID returns a new random request ID.
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID returns true if id is non-empty, at most 128 characters
+// long, and contains only printable ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,48 @@
+package rpc_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test request IDs.
+func TestWithRequestID(t *testing.T) {
+	t.Run("with a request id header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("X-Request-ID", "abc123")
+		r = rpc.WithRequestID(r)
+		id, ok := rpc.RequestIDFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "abc123", id)
+	})
+
+	t.Run("without a request id header", func(t *testing.T) {
+		r := rpc.WithRequestID(httptest.NewRequest("POST", "/", nil))
+		id, ok := rpc.RequestIDFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Len(t, id, 32)
+	})
+
+	t.Run("with a malformed request id header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("X-Request-ID", strings.Repeat("a", 200))
+		r = rpc.WithRequestID(r)
+		id, _ := rpc.RequestIDFromContext(r.Context())
+		assert.Len(t, id, 32)
+	})
+
+	t.Run("with an error response", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("X-Request-ID", "abc123")
+		r = rpc.WithRequestID(r)
+		w := httptest.NewRecorder()
+		rpc.WriteError(rpc.NewResponseWriter(w, r), rpc.NotFound("Pet not found"))
+		assert.Equal(t, "abc123", w.Header().Get("X-Request-ID"))
+		assert.Equal(t, "{\n  \"type\": \"not_found\",\n  \"message\": \"Pet not found\",\n  \"request_id\": \"abc123\"\n}", strings.TrimSpace(w.Body.String()))
+	})
+}
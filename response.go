@@ -5,15 +5,39 @@ import (
 )
 
 // WriteResponse writes a JSON response, or 204 if the value is nil
-// to indicate there is no content.
+// to indicate there is no content. Streams returned by NewStream were
+// already written, and only end with a response header when nothing
+// was sent.
+//
+// If w was returned by NewResponseWriter the response is encoded
+// with the registered codec best matching the request's Accept header.
 func WriteResponse(w http.ResponseWriter, value interface{}) {
+	if s, ok := value.(*Stream); ok {
+		s.end()
+		return
+	}
+
 	if value == nil {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	enc.Encode(value)
+	r, ok := requestFromWriter(w)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(value)
+		return
+	}
+
+	codec := negotiate(r.Header.Get("Accept"))
+	w.Header().Add("Vary", "Accept")
+	b, err := codec.Marshal(value)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeBody(w, http.StatusOK, codec.ContentType(), b)
 }
@@ -1,6 +1,8 @@
 package rpc_test
 
 import (
+	"compress/gzip"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -49,6 +51,60 @@ func TestWriteResponse(t *testing.T) {
 	})
 }
 
+// Test response compression.
+func TestWriteResponse_compression(t *testing.T) {
+	value := map[string]string{"name": strings.Repeat("Tobi", 500)}
+
+	t.Run("with gzip accepted", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(rpc.NewResponseWriter(w, r), value)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, []string{"Accept", "Accept-Encoding"}, w.Header().Values("Vary"))
+
+		gz, err := gzip.NewReader(w.Body)
+		assert.NoError(t, err)
+		b, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), `"name": "TobiTobi`)
+	})
+
+	t.Run("with zstd preferred", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip;q=0.5, zstd")
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(rpc.NewResponseWriter(w, r), value)
+		assert.Equal(t, "zstd", w.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("with a body below the threshold", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(rpc.NewResponseWriter(w, r), map[string]string{"name": "Tobi"})
+		assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "{\n  \"name\": \"Tobi\"\n}\n", w.Body.String())
+	})
+
+	t.Run("with compression disabled", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(rpc.NewResponseWriter(w, r, rpc.CompressionThreshold(0)), value)
+		assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("with an error", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "*")
+		w := httptest.NewRecorder()
+		rpc.WriteError(rpc.NewResponseWriter(w, r, rpc.CompressionThreshold(1)), rpc.BadRequest("Invalid method"))
+		assert.Equal(t, 400, w.Code)
+		assert.Equal(t, "zstd", w.Header().Get("Content-Encoding"))
+	})
+}
+
 // Benchmark responses.
 func BenchmarkWriteResponse(b *testing.B) {
 	b.ReportAllocs()
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/xeipuuv/gojsonschema"
 )
@@ -73,22 +74,43 @@ func (t *TypeObject) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// MarshalJSON implementation.
+func (t TypeObject) MarshalJSON() ([]byte, error) {
+	if t.Ref.Value != "" {
+		return json.Marshal(t.Ref)
+	}
+
+	return json.Marshal(t.Type)
+}
+
 // ItemsObject model.
 type ItemsObject struct {
 	Type Kind `json:"type"`
 	Ref
 }
 
+// MarshalJSON implementation.
+func (i ItemsObject) MarshalJSON() ([]byte, error) {
+	if i.Ref.Value != "" {
+		return json.Marshal(i.Ref)
+	}
+
+	return json.Marshal(struct {
+		Type Kind `json:"type"`
+	}{i.Type})
+}
+
 // Schema model.
 type Schema struct {
 	Name        string          `json:"name"`
 	Version     string          `json:"version"`
-	Description string          `json:"description"`
+	Description string          `json:"description,omitempty"`
 	Methods     []Method        `json:"methods"`
-	Groups      []Group         `json:"groups"`
-	Types       map[string]Type `json:"types"`
+	Groups      []Group         `json:"groups,omitempty"`
+	Types       map[string]Type `json:"types,omitempty"`
+	Limits      *Limits         `json:"limits,omitempty"`
 	Go          struct {
-		Tags []string `json:"tags"`
+		Tags []string `json:"tags,omitempty"`
 	} `json:"go"`
 }
 
@@ -96,17 +118,27 @@ type Schema struct {
 type Method struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
-	Private     bool            `json:"private"`
-	Group       string          `json:"group"`
-	Inputs      []Field         `json:"inputs"`
-	Outputs     []Field         `json:"outputs"`
-	Examples    []MethodExample `json:"examples"`
+	Private     bool            `json:"private,omitempty"`
+	Group       string          `json:"group,omitempty"`
+	Inputs      []Field         `json:"inputs,omitempty"`
+	Outputs     []Field         `json:"outputs,omitempty"`
+	Stream      bool            `json:"stream,omitempty"`
+	Examples    []MethodExample `json:"examples,omitempty"`
+	Limits      *Limits         `json:"limits,omitempty"`
+}
+
+// Limits model.
+type Limits struct {
+	BodyBytes    int64 `json:"body_bytes,omitempty"`
+	Depth        int   `json:"depth,omitempty"`
+	ArrayLength  int   `json:"array_length,omitempty"`
+	StringLength int   `json:"string_length,omitempty"`
 }
 
 // MethodExample model.
 type MethodExample struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
 	Input       interface{} `json:"input"`
 	Output      interface{} `json:"output"`
 }
@@ -114,22 +146,41 @@ type MethodExample struct {
 // Field model.
 type Field struct {
 	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	Required    bool        `json:"required"`
-	ReadOnly    bool        `json:"readonly"`
-	Default     interface{} `json:"default"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	ReadOnly    bool        `json:"readonly,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
 	Type        TypeObject  `json:"type"`
 	Items       ItemsObject `json:"items"`
-	Enum        []string    `json:"enum"`
+	Enum        []string    `json:"enum,omitempty"`
+}
+
+// MarshalJSON implementation.
+func (f Field) MarshalJSON() ([]byte, error) {
+	type field Field
+
+	v := struct {
+		field
+		Items *ItemsObject `json:"items,omitempty"`
+	}{
+		field: field(f),
+	}
+
+	// omit items for non-array fields
+	if f.Type.Type == Array {
+		v.Items = &f.Items
+	}
+
+	return json.Marshal(v)
 }
 
 // Type model.
 type Type struct {
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Private     bool      `json:"private"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Private     bool      `json:"private,omitempty"`
 	Properties  []Field   `json:"properties"`
-	Examples    []Example `json:"examples"`
+	Examples    []Example `json:"examples,omitempty"`
 }
 
 // Example model.
@@ -166,6 +217,66 @@ func (s Schema) TypesSlice() (v []Type) {
 	return
 }
 
+// Public returns a copy of the schema without private methods and types,
+// returning an error when a public method or type refers to a private type.
+func (s Schema) Public() (Schema, error) {
+	methods := []Method{}
+	for _, m := range s.Methods {
+		if m.Private {
+			continue
+		}
+
+		for _, fields := range [][]Field{m.Inputs, m.Outputs} {
+			for _, f := range fields {
+				if t, ok := s.privateRef(f); ok {
+					return Schema{}, fmt.Errorf("method %q field %q refers to private type %q", m.Name, f.Name, t)
+				}
+			}
+		}
+
+		methods = append(methods, m)
+	}
+	s.Methods = methods
+
+	if s.Types != nil {
+		var names []string
+		for name := range s.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		types := make(map[string]Type)
+		for _, name := range names {
+			t := s.Types[name]
+			if t.Private {
+				continue
+			}
+
+			for _, f := range t.Properties {
+				if ref, ok := s.privateRef(f); ok {
+					return Schema{}, fmt.Errorf("type %q field %q refers to private type %q", name, f.Name, ref)
+				}
+			}
+
+			types[name] = t
+		}
+		s.Types = types
+	}
+
+	return s, nil
+}
+
+// privateRef returns the name of the private type referenced by field f, if any.
+func (s Schema) privateRef(f Field) (string, bool) {
+	for _, ref := range []Ref{f.Type.Ref, f.Items.Ref} {
+		name := strings.TrimPrefix(ref.Value, "#/types/")
+		if t, ok := s.Types[name]; ok && ref.Value != "" && t.Private {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 // Load returns a schema loaded and validated from path.
 func Load(path string) (*Schema, error) {
 	// TODO: bake into the binary with Go's native 'embed' stuff once it's available
@@ -237,6 +348,35 @@ func Load(path string) (*Schema, error) {
 	return &s, nil
 }
 
+// MethodLimits returns the limits of method m, with method limits overriding the schema limits.
+func (s Schema) MethodLimits(m Method) (l Limits) {
+	if s.Limits != nil {
+		l = *s.Limits
+	}
+
+	if m.Limits == nil {
+		return
+	}
+
+	if m.Limits.BodyBytes != 0 {
+		l.BodyBytes = m.Limits.BodyBytes
+	}
+
+	if m.Limits.Depth != 0 {
+		l.Depth = m.Limits.Depth
+	}
+
+	if m.Limits.ArrayLength != 0 {
+		l.ArrayLength = m.Limits.ArrayLength
+	}
+
+	if m.Limits.StringLength != 0 {
+		l.StringLength = m.Limits.StringLength
+	}
+
+	return
+}
+
 // IsBuiltin returns true if the type is built-in.
 func IsBuiltin(kind Kind) bool {
 	switch kind {
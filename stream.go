@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Stream is a response stream of method outputs, written as newline-delimited
+// JSON objects with a "result", or as Server-Sent Events named "message" when
+// the request's Accept header allows text/event-stream. Outputs are flushed as
+// they are sent. Errors after the first output end the stream with an object
+// with the "status" code and "error" in the WriteError shape, sent as an
+// event named "error" for Server-Sent Events.
+type Stream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	ctx     context.Context
+	sse     bool
+	started bool
+}
+
+// NewStream returns a new stream responding to request r with w, which must
+// be returned by NewResponseWriter for WriteError to report errors after the
+// first output was sent. A nil w, passed to calls of batch requests and
+// WebSocket connections, returns a bad request error as streams are not
+// supported by these transports, as does a request of a transport rejecting
+// streams before they are started, such as JSONRPCHandler.
+func NewStream(w http.ResponseWriter, r *http.Request) (*Stream, error) {
+	if w == nil {
+		return nil, BadRequest("Streaming methods cannot be batched or called over WebSocket")
+	}
+
+	if err, ok := r.Context().Value(noStreamsKey{}).(error); ok {
+		return nil, err
+	}
+
+	s := &Stream{
+		w:   w,
+		ctx: r.Context(),
+		sse: strings.Contains(r.Header.Get("Accept"), "text/event-stream"),
+	}
+
+	if rw, ok := w.(*responseWriter); ok {
+		rw.stream = s
+	}
+
+	return s, nil
+}
+
+// noStreamsKey is a private context key.
+type noStreamsKey struct{}
+
+// withoutStreams returns a copy of r for which NewStream returns err.
+func withoutStreams(r *http.Request, err error) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), noStreamsKey{}, err))
+}
+
+// start writes the response header unless already written. The mutex must be held.
+func (s *Stream) start() {
+	if s.started {
+		return
+	}
+
+	s.started = true
+	h := s.w.Header()
+	if s.sse {
+		h.Set("Content-Type", "text/event-stream")
+	} else {
+		h.Set("Content-Type", "application/x-ndjson")
+	}
+	h.Set("Cache-Control", "no-store")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// write writes value v as an event named event for Server-Sent Events, or
+// otherwise as a line, and flushes it. The mutex must be held.
+func (s *Stream) write(event string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if s.sse {
+		b = append(append([]byte("event: "+event+"\ndata: "), b...), "\n\n"...)
+	} else {
+		b = append(b, '\n')
+	}
+
+	s.start()
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	return nil
+}
+
+// Send sends output v, returning the context error when the client disconnected.
+func (s *Stream) Send(v interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sse {
+		return s.write("message", v)
+	}
+	return s.write("message", map[string]interface{}{"result": v})
+}
+
+// Started returns true if the response header was written.
+func (s *Stream) Started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// end ends a stream without outputs, writing the response header.
+func (s *Stream) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.start()
+}
+
+// writeError writes the error response status and body as the final message.
+func (s *Stream) writeError(status int, body ErrorResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write("error", CallResult{Status: status, Error: &body})
+}
@@ -0,0 +1,106 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test streams.
+func TestStream(t *testing.T) {
+	serve := func(accept string, fn func(*rpc.Stream) error) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/tail_logs", nil)
+		r.Header.Set("Accept", accept)
+		r.Header.Set(rpc.RequestIDHeader, "abc")
+		r = rpc.WithRequestID(r)
+		rec := httptest.NewRecorder()
+		w := rpc.NewResponseWriter(rec, r)
+
+		s, err := rpc.NewStream(w, r)
+		assert.NoError(t, err)
+
+		err = fn(s)
+		if err != nil {
+			rpc.WriteError(w, err)
+			return rec
+		}
+
+		rpc.WriteResponse(w, s)
+		return rec
+	}
+
+	t.Run("with ndjson", func(t *testing.T) {
+		w := serve("application/x-ndjson", func(s *rpc.Stream) error {
+			assert.NoError(t, s.Send(map[string]string{"message": "hello"}))
+			assert.NoError(t, s.Send(map[string]string{"message": "world"}))
+			return nil
+		})
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.True(t, w.Flushed)
+		assert.Equal(t, `{"result":{"message":"hello"}}`+"\n"+`{"result":{"message":"world"}}`+"\n", w.Body.String())
+	})
+
+	t.Run("with sse", func(t *testing.T) {
+		w := serve("text/event-stream", func(s *rpc.Stream) error {
+			assert.NoError(t, s.Send(map[string]string{"message": "hello"}))
+			return nil
+		})
+
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "event: message\ndata: {\"message\":\"hello\"}\n\n", w.Body.String())
+	})
+
+	t.Run("with an error after sending", func(t *testing.T) {
+		w := serve("application/x-ndjson", func(s *rpc.Stream) error {
+			assert.NoError(t, s.Send(map[string]string{"message": "hello"}))
+			return rpc.NotFound("Log not found")
+		})
+
+		assert.Equal(t, 200, w.Code)
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Equal(t, `{"status":404,"error":{"type":"not_found","message":"Log not found","request_id":"abc"}}`, lines[1])
+	})
+
+	t.Run("with an error before sending", func(t *testing.T) {
+		w := serve("application/x-ndjson", func(s *rpc.Stream) error {
+			return rpc.NotFound("Log not found")
+		})
+
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("without outputs", func(t *testing.T) {
+		w := serve("application/x-ndjson", func(s *rpc.Stream) error {
+			return nil
+		})
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("with a canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		r := httptest.NewRequest("POST", "/tail_logs", nil).WithContext(ctx)
+		s, err := rpc.NewStream(httptest.NewRecorder(), r)
+		assert.NoError(t, err)
+		assert.Equal(t, context.Canceled, s.Send("hello"))
+	})
+
+	t.Run("in a batch", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/tail_logs", nil)
+		_, err := rpc.NewStream(nil, r)
+		assert.EqualError(t, err, "Streaming methods cannot be batched or called over WebSocket")
+	})
+}
@@ -0,0 +1,168 @@
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceContext is a W3C trace context, propagated by the
+// traceparent and tracestate headers.
+type TraceContext struct {
+	// TraceID is the 32 character hex trace ID.
+	TraceID string
+
+	// ParentID is the 16 character hex ID of the parent span.
+	ParentID string
+
+	// Flags are the trace flags.
+	Flags byte
+
+	// State is the vendor-specific tracestate header value, if any.
+	State string
+}
+
+// Sampled returns true if the sampled flag is set.
+func (t TraceContext) Sampled() bool {
+	return t.Flags&1 == 1
+}
+
+// TraceParent returns the traceparent header value.
+func (t TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", t.TraceID, t.ParentID, t.Flags)
+}
+
+// ParseTraceParent parses the traceparent header value s, returning false
+// when malformed. Future versions are parsed as version 00 per the spec.
+func ParseTraceParent(s string) (TraceContext, bool) {
+	var t TraceContext
+
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 {
+		return t, false
+	}
+
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return t, false
+	}
+
+	if !isHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return t, false
+	}
+
+	if !isHex(parentID, 16) || parentID == strings.Repeat("0", 16) {
+		return t, false
+	}
+
+	if !isHex(flags, 2) {
+		return t, false
+	}
+
+	b, _ := hex.DecodeString(flags)
+	t.TraceID = traceID
+	t.ParentID = parentID
+	t.Flags = b[0]
+	return t, true
+}
+
+// isHex returns true if s is n lowercase hex characters.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// traceKey is a private context key.
+type traceKey struct{}
+
+// NewTraceContext returns a new context with trace context t.
+func NewTraceContext(ctx context.Context, t TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// TraceContextFromContext returns the trace context from context.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	v, ok := ctx.Value(traceKey{}).(TraceContext)
+	return v, ok
+}
+
+// WithTraceContext returns a shallow copy of r with a context containing the
+// trace context parsed from the traceparent and tracestate headers, or r
+// when the traceparent header is missing or malformed.
+func WithTraceContext(r *http.Request) *http.Request {
+	t, ok := ParseTraceParent(r.Header.Get("traceparent"))
+	if !ok {
+		return r
+	}
+
+	t.State = strings.Join(r.Header.Values("tracestate"), ",")
+	return r.WithContext(NewTraceContext(r.Context(), t))
+}
+
+// Span is a tracing span.
+type Span interface {
+	// TraceContext returns the trace context of the span, propagated to downstream calls.
+	TraceContext() TraceContext
+
+	// End ends the span with the error of the method, if any.
+	End(err error)
+}
+
+// Tracer is the interface used for starting spans.
+type Tracer interface {
+	// Start starts a span named name, with the parent trace context, which
+	// is the zero value when the request did not provide a traceparent.
+	Start(ctx context.Context, name string, parent TraceContext) Span
+}
+
+// DefaultTracer is the tracer used by StartSpan, spans are not started when nil.
+var DefaultTracer Tracer
+
+// spanKey is a private context key.
+type spanKey struct{}
+
+// SpanFromContext returns the span from context.
+func SpanFromContext(ctx context.Context) (Span, bool) {
+	v, ok := ctx.Value(spanKey{}).(Span)
+	return v, ok
+}
+
+// StartSpan starts a span named name using DefaultTracer, returning a context
+// containing the span and its trace context, and a function which must be
+// deferred to end the span with the error pointed to by err. Panics end the
+// span with a PanicError, and are then propagated.
+//
+//	ctx, end := rpc.StartSpan(ctx, "add_item")
+//	defer end(&err)
+func StartSpan(ctx context.Context, name string) (context.Context, func(err *error)) {
+	if DefaultTracer == nil {
+		return ctx, func(*error) {}
+	}
+
+	parent, _ := TraceContextFromContext(ctx)
+	span := DefaultTracer.Start(ctx, name, parent)
+	ctx = context.WithValue(ctx, spanKey{}, span)
+	ctx = NewTraceContext(ctx, span.TraceContext())
+
+	return ctx, func(err *error) {
+		if v := recover(); v != nil {
+			span.End(PanicError{Method: name, Value: v})
+			panic(v)
+		}
+
+		span.End(*err)
+	}
+}
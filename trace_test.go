@@ -0,0 +1,112 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// testTracer is a tracer recording spans.
+type testTracer struct {
+	spans []*testSpan
+}
+
+// Start implementation.
+func (t *testTracer) Start(ctx context.Context, name string, parent rpc.TraceContext) rpc.Span {
+	s := &testSpan{name: name, parent: parent}
+	t.spans = append(t.spans, s)
+	return s
+}
+
+// testSpan is a recorded span.
+type testSpan struct {
+	name   string
+	parent rpc.TraceContext
+	err    error
+	ended  bool
+}
+
+// TraceContext implementation.
+func (s *testSpan) TraceContext() rpc.TraceContext {
+	t := s.parent
+	t.ParentID = "00f067aa0ba902b7"
+	return t
+}
+
+// End implementation.
+func (s *testSpan) End(err error) {
+	s.err = err
+	s.ended = true
+}
+
+// Test trace context parsing.
+func TestParseTraceParent(t *testing.T) {
+	t.Run("with a valid header", func(t *testing.T) {
+		tc, ok := rpc.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		assert.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", tc.ParentID)
+		assert.True(t, tc.Sampled())
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.TraceParent())
+	})
+
+	t.Run("with a future version", func(t *testing.T) {
+		_, ok := rpc.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+		assert.True(t, ok)
+	})
+
+	t.Run("with malformed headers", func(t *testing.T) {
+		for _, s := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		} {
+			_, ok := rpc.ParseTraceParent(s)
+			assert.False(t, ok, s)
+		}
+	})
+}
+
+// Test spans.
+func TestStartSpan(t *testing.T) {
+	tracer := &testTracer{}
+	rpc.DefaultTracer = tracer
+	defer func() {
+		rpc.DefaultTracer = nil
+	}()
+
+	r := httptest.NewRequest("POST", "/add_item", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01")
+	r.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	r = rpc.WithTraceContext(r)
+
+	invoke := func(ctx context.Context) (err error) {
+		ctx, end := rpc.StartSpan(ctx, "add_item")
+		defer end(&err)
+
+		_, ok := rpc.SpanFromContext(ctx)
+		assert.True(t, ok)
+
+		tc, _ := rpc.TraceContextFromContext(ctx)
+		assert.Equal(t, "00f067aa0ba902b7", tc.ParentID)
+		return rpc.NotFound("Item not found")
+	}
+
+	err := invoke(r.Context())
+	assert.EqualError(t, err, "Item not found")
+
+	span := tracer.spans[0]
+	assert.Equal(t, "add_item", span.name)
+	assert.Equal(t, "b7ad6b7169203331", span.parent.ParentID)
+	assert.Equal(t, "congo=t61rcWkgMzE", span.parent.State)
+	assert.True(t, span.ended)
+	assert.Equal(t, err, span.err)
+}
@@ -0,0 +1,259 @@
+// Package websocket provides a WebSocket transport for generated servers,
+// multiplexing calls and server notifications over a single connection.
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+
+	"github.com/apex/rpc"
+)
+
+// DefaultConcurrency is the default maximum number of calls of a connection run concurrently.
+const DefaultConcurrency = 16
+
+// DefaultMaxMessageBytes is the default maximum size in bytes of messages received.
+const DefaultMaxMessageBytes = 1 << 20
+
+// DefaultPingInterval is the default interval of pings keeping connections alive.
+const DefaultPingInterval = 30 * time.Second
+
+// writeTimeout is the timeout of writing a message.
+const writeTimeout = 10 * time.Second
+
+// Connector is the interface used for servers notified of connections.
+type Connector interface {
+	Connect(conn *Conn)
+}
+
+// Option is a Serve option.
+type Option func(*config)
+
+// config is the Serve configuration.
+type config struct {
+	concurrency     int
+	maxMessageBytes int64
+	pingInterval    time.Duration
+	checkOrigin     func(r *http.Request) bool
+}
+
+// Concurrency sets the maximum number of calls of a connection run
+// concurrently, defaulting to DefaultConcurrency.
+func Concurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// MaxMessageBytes sets the maximum size in bytes of messages received,
+// defaulting to DefaultMaxMessageBytes. Larger messages close the connection.
+func MaxMessageBytes(n int64) Option {
+	return func(c *config) {
+		c.maxMessageBytes = n
+	}
+}
+
+// PingInterval sets the interval of pings keeping connections alive,
+// defaulting to DefaultPingInterval. Zero or less disables pings.
+func PingInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.pingInterval = d
+	}
+}
+
+// CheckOrigin sets the function used to accept the Origin header of requests,
+// defaulting to accepting requests without an Origin header, or with an Origin
+// host matching the Host header.
+func CheckOrigin(fn func(r *http.Request) bool) Option {
+	return func(c *config) {
+		c.checkOrigin = fn
+	}
+}
+
+// call is a call received over a connection.
+type call struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Input  json.RawMessage `json:"input"`
+}
+
+// result is the result of a call sent over a connection.
+type result struct {
+	ID uint64 `json:"id"`
+	rpc.CallResult
+}
+
+// event is a notification sent over a connection.
+type event struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// connKey is a private context key.
+type connKey struct{}
+
+// FromContext returns the connection of a call from ctx.
+func FromContext(ctx context.Context) (*Conn, bool) {
+	v, ok := ctx.Value(connKey{}).(*Conn)
+	return v, ok
+}
+
+// Conn is a connection served by Serve.
+type Conn struct {
+	mu     sync.Mutex
+	conn   *gorilla.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Context returns the context of the connection, which is canceled when it is closed.
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Notify sends a notification named event with data v, which may be nil.
+func (c *Conn) Notify(name string, v interface{}) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.write(event{Event: name, Data: v})
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.cancel()
+	return c.conn.Close()
+}
+
+// write writes value v as a text message.
+func (c *Conn) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.conn.WriteMessage(gorilla.TextMessage, b)
+}
+
+// ping writes pings every interval d until the connection is closed.
+func (c *Conn) ping(d time.Duration) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-t.C:
+			err := c.conn.WriteControl(gorilla.PingMessage, nil, time.Now().Add(writeTimeout))
+			if err != nil {
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+// Serve upgrades request r to a WebSocket connection multiplexing calls,
+// JSON messages with an "id", method name and input, invoking each with fn
+// and responding with a message with the same "id", a status code and either
+// the result or an error in the rpc.WriteError shape. The server s is notified
+// of the connection if it implements the Connector interface, and may send
+// notifications, JSON messages with an "event" name and "data", with the
+// connection, also available to calls with FromContext.
+//
+// The calls are passed a copy of r with the input as its body and a new request
+// ID, and panics are recovered per call. Browsers cannot set the Authorization
+// header of WebSocket requests, so a bearer token may be provided with the
+// access_token query parameter instead.
+func Serve(w http.ResponseWriter, r *http.Request, s interface{}, fn rpc.CallFunc, options ...Option) {
+	c := config{
+		concurrency:     DefaultConcurrency,
+		maxMessageBytes: DefaultMaxMessageBytes,
+		pingInterval:    DefaultPingInterval,
+	}
+
+	for _, o := range options {
+		o(&c)
+	}
+
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+
+	// the upgrade hijacks the underlying connection,
+	// so unwrap the writer of rpc.NewResponseWriter
+	if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+		w = u.Unwrap()
+	}
+
+	upgrader := gorilla.Upgrader{
+		CheckOrigin: c.checkOrigin,
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader responds with the error
+		return
+	}
+
+	ws.SetReadLimit(c.maxMessageBytes)
+
+	// access token
+	if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	conn := &Conn{conn: ws}
+	conn.ctx, conn.cancel = context.WithCancel(context.WithValue(r.Context(), connKey{}, conn))
+	defer conn.Close()
+
+	if c.pingInterval > 0 {
+		go conn.ping(c.pingInterval)
+	}
+
+	if h, ok := s.(Connector); ok {
+		h.Connect(conn)
+	}
+
+	r = r.WithContext(conn.ctx)
+	r.Method = "POST"
+	sem := make(chan struct{}, c.concurrency)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		_, b, err := ws.ReadMessage()
+		if err != nil {
+			conn.cancel()
+			return
+		}
+
+		var m call
+		err = json.Unmarshal(b, &m)
+		if err != nil {
+			conn.write(result{CallResult: rpc.ErrorResult(r, rpc.BadRequest("Malformed message"))})
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(m call) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r := r.WithContext(rpc.NewRequestIDContext(r.Context(), rpc.NewRequestID()))
+			conn.write(result{ID: m.ID, CallResult: rpc.InvokeCall(r, m.Method, m.Input, fn)})
+		}(m)
+	}
+}
@@ -0,0 +1,210 @@
+package websocket_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+	"github.com/apex/rpc/transport/websocket"
+)
+
+// server implementation.
+type server struct{}
+
+// Connect implementation.
+func (server) Connect(conn *websocket.Conn) {
+	conn.Notify("connected", nil)
+}
+
+// call implementation.
+func call(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) (interface{}, error) {
+	switch path {
+	case "/add_item":
+		var in struct {
+			Item string `json:"item"`
+		}
+		err := rpc.ReadRequest(r, &in)
+		if err != nil {
+			return nil, err
+		}
+		if in.Item == "" {
+			return nil, rpc.ValidationErrors{{Field: "item", Message: "is required"}}
+		}
+		return nil, nil
+	case "/get_items":
+		return map[string]interface{}{"items": []string{"cook"}}, nil
+	case "/panic":
+		panic("boom")
+	case "/subscribe":
+		conn, ok := websocket.FromContext(ctx)
+		if !ok {
+			return nil, rpc.BadRequest("Subscriptions require a WebSocket")
+		}
+		conn.Notify("item_added", map[string]interface{}{"item": "cook"})
+		return nil, nil
+	case "/whoami":
+		return map[string]interface{}{"authorization": r.Header.Get("Authorization")}, nil
+	case "/stream":
+		stream, err := rpc.NewStream(w, r)
+		if err != nil {
+			return nil, err
+		}
+		return stream, stream.Send(map[string]interface{}{"item": "cook"})
+	default:
+		return nil, rpc.ErrInvalidMethod
+	}
+}
+
+// dial returns a connection to a new test server serving WebSocket
+// connections, with query string q.
+func dial(t testing.TB, q string, options ...websocket.Option) *gorilla.Conn {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = rpc.WithRequestID(r)
+		w = rpc.NewResponseWriter(w, r)
+		websocket.Serve(w, r, server{}, call, options...)
+	}))
+	t.Cleanup(ts.Close)
+
+	conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/_websocket"+q, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	// connected notification
+	var msg map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, map[string]interface{}{"event": "connected"}, msg)
+
+	return conn
+}
+
+// Test WebSocket connections.
+func TestServe(t *testing.T) {
+	defer func(fn func(*http.Request, rpc.PanicError)) {
+		rpc.OnPanic = fn
+	}(rpc.OnPanic)
+	rpc.OnPanic = nil
+
+	t.Run("with a result", func(t *testing.T) {
+		conn := dial(t, "")
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": 1, "method": "get_items"}))
+
+		var msg map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, map[string]interface{}{
+			"id":     1.0,
+			"status": 200.0,
+			"result": map[string]interface{}{"items": []interface{}{"cook"}},
+		}, msg)
+	})
+
+	t.Run("with no result", func(t *testing.T) {
+		conn := dial(t, "")
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": 1, "method": "add_item", "input": map[string]interface{}{"item": "cook"}}))
+
+		var msg map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, map[string]interface{}{"id": 1.0, "status": 204.0}, msg)
+	})
+
+	t.Run("with an error", func(t *testing.T) {
+		conn := dial(t, "")
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": 5, "method": "add_item"}))
+
+		var msg struct {
+			ID     int                    `json:"id"`
+			Status int                    `json:"status"`
+			Error  map[string]interface{} `json:"error"`
+		}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, 5, msg.ID)
+		assert.Equal(t, 400, msg.Status)
+		assert.Equal(t, "invalid", msg.Error["type"])
+		assert.Len(t, msg.Error["request_id"], 32)
+	})
+
+	t.Run("with a panic", func(t *testing.T) {
+		conn := dial(t, "")
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": 1, "method": "panic"}))
+
+		var msg map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, 500.0, msg["status"])
+	})
+
+	t.Run("with a stream", func(t *testing.T) {
+		conn := dial(t, "")
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": 1, "method": "stream"}))
+
+		var msg map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, 400.0, msg["status"])
+		assert.Equal(t, "Streaming methods cannot be batched or called over WebSocket", msg["error"].(map[string]interface{})["message"])
+	})
+
+	t.Run("with a notification", func(t *testing.T) {
+		conn := dial(t, "")
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": 1, "method": "subscribe"}))
+
+		var msg map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, map[string]interface{}{"event": "item_added", "data": map[string]interface{}{"item": "cook"}}, msg)
+
+		msg = nil
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, map[string]interface{}{"id": 1.0, "status": 204.0}, msg)
+	})
+
+	t.Run("with concurrent calls", func(t *testing.T) {
+		conn := dial(t, "", websocket.Concurrency(4))
+		for i := 1; i <= 10; i++ {
+			assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": i, "method": "get_items"}))
+		}
+
+		ids := map[float64]bool{}
+		for i := 1; i <= 10; i++ {
+			var msg map[string]interface{}
+			assert.NoError(t, conn.ReadJSON(&msg))
+			assert.Equal(t, 200.0, msg["status"])
+			ids[msg["id"].(float64)] = true
+		}
+		assert.Len(t, ids, 10)
+	})
+
+	t.Run("with an access token", func(t *testing.T) {
+		conn := dial(t, "?access_token=secret")
+		assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": 1, "method": "whoami"}))
+
+		var msg map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, map[string]interface{}{"authorization": "Bearer secret"}, msg["result"])
+	})
+
+	t.Run("with a malformed message", func(t *testing.T) {
+		conn := dial(t, "")
+		assert.NoError(t, conn.WriteMessage(gorilla.TextMessage, []byte(`{`)))
+
+		var msg map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, 0.0, msg["id"])
+		assert.Equal(t, 400.0, msg["status"])
+		assert.Equal(t, "Malformed message", msg["error"].(map[string]interface{})["message"])
+	})
+
+	t.Run("with a cross-origin request", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			websocket.Serve(w, r, nil, call)
+		}))
+		defer ts.Close()
+
+		header := http.Header{"Origin": {"https://example.com"}}
+		_, res, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), header)
+		assert.Error(t, err)
+		assert.Equal(t, 403, res.StatusCode)
+	})
+}
@@ -1,12 +1,28 @@
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
 
 // Validator is the interface used for validating input.
 type Validator interface {
 	Validate() error
 }
 
+// IsZero returns true if v is nil or the zero value of its type, used by
+// generated validation to skip optional fields which are not present.
+func IsZero(v interface{}) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+// FieldsProvider is the interface used for providing field validation errors.
+type FieldsProvider interface {
+	Fields() []ValidationError
+}
+
 // ValidationError is a field validation error.
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -17,3 +33,90 @@ type ValidationError struct {
 func (e ValidationError) Error() string {
 	return fmt.Sprintf("%s %s", e.Field, e.Message)
 }
+
+// ValidationErrors is a list of field validation errors, which implements
+// StatusProvider, TypeProvider and FieldsProvider.
+type ValidationErrors []ValidationError
+
+// Add a validation error for field.
+func (e *ValidationErrors) Add(field, message string) {
+	*e = append(*e, ValidationError{
+		Field:   field,
+		Message: message,
+	})
+}
+
+// Merge adds the validation errors of err, returned by the validation of
+// the value at path, prefixing their fields with path. Errors which are
+// not validation errors are added as a failure of path itself.
+func (e *ValidationErrors) Merge(path string, err error) {
+	switch err := err.(type) {
+	case nil:
+	case ValidationErrors:
+		for _, v := range err {
+			e.Add(joinPath(path, v.Field), v.Message)
+		}
+	case ValidationError:
+		e.Add(joinPath(path, err.Field), err.Message)
+	default:
+		e.Add(path, err.Error())
+	}
+}
+
+// Err returns the validation errors as an error, or nil when empty.
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// StatusCode implementation.
+func (e ValidationErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Type implementation.
+func (e ValidationErrors) Type() string {
+	return "invalid"
+}
+
+// Fields implementation.
+func (e ValidationErrors) Fields() []ValidationError {
+	return e
+}
+
+// Error implementation.
+func (e ValidationErrors) Error() string {
+	var s []string
+	for _, v := range e {
+		s = append(s, v.Error())
+	}
+	return strings.Join(s, "; ")
+}
+
+// joinPath returns the JSON path of field relative to path.
+func joinPath(path, field string) string {
+	switch {
+	case path == "":
+		return field
+	case field == "":
+		return path
+	case strings.HasPrefix(field, "["):
+		return path + field
+	default:
+		return path + "." + field
+	}
+}
+
+// validationError returns err as a validation error.
+func validationError(err error) error {
+	switch err := err.(type) {
+	case ValidationErrors:
+		return err
+	case ValidationError:
+		return ValidationErrors{err}
+	default:
+		return Invalid(err.Error())
+	}
+}
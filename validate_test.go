@@ -0,0 +1,112 @@
+package rpc_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// pet model.
+type pet struct {
+	Name string `json:"name"`
+	Tags []tag  `json:"tags"`
+}
+
+// Validate implementation.
+func (p *pet) Validate() error {
+	var errs rpc.ValidationErrors
+
+	if p.Name == "" {
+		errs.Add("name", "is required")
+	}
+
+	for i, v := range p.Tags {
+		errs.Merge(fmt.Sprintf("tags[%d]", i), v.Validate())
+	}
+
+	return errs.Err()
+}
+
+// tag model.
+type tag struct {
+	Name string `json:"name"`
+}
+
+// Validate implementation.
+func (t *tag) Validate() error {
+	var errs rpc.ValidationErrors
+
+	if t.Name == "" {
+		errs.Add("name", "is required")
+	}
+
+	return errs.Err()
+}
+
+// Test validation errors.
+func TestValidationErrors(t *testing.T) {
+	t.Run("with no errors", func(t *testing.T) {
+		var errs rpc.ValidationErrors
+		assert.NoError(t, errs.Err())
+	})
+
+	t.Run("with merged errors", func(t *testing.T) {
+		var errs rpc.ValidationErrors
+		errs.Add("name", "is required")
+		errs.Merge("items[2]", rpc.ValidationErrors{{Field: "text", Message: "is required"}})
+		errs.Merge("owner", rpc.ValidationError{Field: "email", Message: "is invalid"})
+		errs.Merge("tags", errors.New("must be unique"))
+		errs.Merge("meta", nil)
+
+		assert.Equal(t, rpc.ValidationErrors{
+			{Field: "name", Message: "is required"},
+			{Field: "items[2].text", Message: "is required"},
+			{Field: "owner.email", Message: "is invalid"},
+			{Field: "tags", Message: "must be unique"},
+		}, errs)
+
+		assert.EqualError(t, errs.Err(), "name is required; items[2].text is required; owner.email is invalid; tags must be unique")
+	})
+}
+
+// Test validation of requests.
+func TestReadRequest_validation(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "tags": [{ "name": "ferret" }, {}] }`))
+	r.Header.Set("Content-Type", "application/json")
+
+	var in pet
+	err := rpc.ReadRequest(r, &in)
+	assert.EqualError(t, err, `name is required; tags[1].name is required`)
+
+	w := httptest.NewRecorder()
+	rpc.WriteError(w, err)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, `{
+  "type": "invalid",
+  "message": "name is required; tags[1].name is required",
+  "fields": [
+    {
+      "field": "name",
+      "message": "is required"
+    },
+    {
+      "field": "tags[1].name",
+      "message": "is required"
+    }
+  ]
+}`, strings.TrimSpace(w.Body.String()))
+}
+
+// Test zero values.
+func TestIsZero(t *testing.T) {
+	assert.True(t, rpc.IsZero(nil))
+	assert.True(t, rpc.IsZero(pet{}))
+	assert.False(t, rpc.IsZero(pet{Tags: []tag{}}))
+	assert.False(t, rpc.IsZero(tag{Name: "ferret"}))
+}